@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func main() {
+	locationName := flag.String("location", uv.TelAviv.DisplayName, "display name of the location to backfill")
+	from := flag.String("from", "", "first date to backfill, as YYYY-MM-DD")
+	to := flag.String("to", "", "last date to backfill, as YYYY-MM-DD")
+	historyDB := flag.String("db", "history.db", "path of the history database")
+	callInterval := flag.Duration("interval", time.Second, "minimum time between two API calls")
+	backoff := flag.Duration("backoff", time.Minute, "time to wait after the API rate limited a call")
+	quota := flag.Int("quota", 100, "most API calls to spend in this run. They share the daily quota of the app ID with the bot, whose quota counter does not see them")
+	archive := flag.String("import", "", "path of a CSV archive to import instead of calling the API, with observed_at and uv_index columns")
+	flag.Parse()
+
+	if validationError := uv.ValidateLocations(uv.Locations); validationError != nil {
+		log.Fatalln(validationError)
+	}
+	location, locationError := uv.FindLocation(*locationName)
+	if locationError != nil {
+		log.Fatalln(locationError)
+	}
+	if *archive != "" {
+		importArchive(location, *archive, *historyDB)
+		return
+	}
+	start, startError := time.Parse("2006-01-02", *from)
+	if startError != nil {
+		log.Fatalln(fmt.Errorf("invalid -from date: %w", startError))
+	}
+	end, endError := time.Parse("2006-01-02", *to)
+	if endError != nil {
+		log.Fatalln(fmt.Errorf("invalid -to date: %w", endError))
+	}
+	if end.Before(start) {
+		log.Fatalln("The -to date must not be before the -from date")
+	}
+	backfiller := &uv.Backfiller{
+		HistoryDays: uv.OpenWeatherMapHistoryDays,
+		MaxRetries:  5,
+	}
+	if rangeError := backfiller.CheckRange(start); rangeError != nil {
+		log.Fatalln(fmt.Errorf("invalid -from date: %w", rangeError))
+	}
+
+	appID := os.Getenv("OPENWEATHER_MAP_APP_ID")
+	if appID == "" {
+		log.Fatalln("An OpenWeather Map app ID is required. Please set the OPENWEATHER_MAP_APP_ID env var")
+	}
+
+	history, historyError := uv.OpenHistory(*historyDB)
+	if historyError != nil {
+		log.Fatalln(historyError)
+	}
+	defer history.Close()
+
+	exitChan := make(chan bool)
+	go func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+
+		<-c
+		close(exitChan)
+	}()
+
+	openWeatherMap := &uv.OpenWeatherMap{Host: "https://api.openweathermap.org", AppID: appID}
+	backfiller.Provider = &uv.BudgetedHistoricalProvider{Provider: openWeatherMap, Counter: uv.NewQuotaCounter("backfill", *quota)}
+	backfiller.History = history
+	backfiller.CallInterval = *callInterval
+	backfiller.RateLimitBackoff = *backoff
+	backfiller.ExitChan = exitChan
+	if backfillError := backfiller.Backfill(location, start, end.AddDate(0, 0, 1)); backfillError != nil {
+		log.Println(backfillError)
+		log.Println("Run the same command again to resume")
+		return
+	}
+	log.Println("Backfill completed")
+}
+
+func importArchive(location *uv.Location, path string, historyDB string) {
+	file, openError := os.Open(path)
+	if openError != nil {
+		log.Fatalln(fmt.Errorf("failed to open the archive: %w", openError))
+	}
+	defer file.Close()
+
+	history, historyError := uv.OpenHistory(historyDB)
+	if historyError != nil {
+		log.Fatalln(historyError)
+	}
+	defer history.Close()
+
+	imported, importError := history.ImportHistory(location, file)
+	if importError != nil {
+		log.Println(fmt.Errorf("failed to import %s: %w", path, importError))
+		return
+	}
+	log.Printf("Imported %d records of %s\n", imported, location.DisplayName)
+}
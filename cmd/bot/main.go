@@ -1,9 +1,13 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -11,6 +15,10 @@ import (
 )
 
 func main() {
+	if validationError := uv.ValidateLocations(uv.Locations); validationError != nil {
+		log.Fatalln(validationError)
+	}
+
 	exitChan := make(chan bool)
 
 	go func() {
@@ -19,14 +27,118 @@ func main() {
 		signal.Notify(c, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
 		<-c
-		exitChan <- true
+		close(exitChan)
 	}()
 
 	appID := os.Getenv("OPENWEATHER_MAP_APP_ID")
 	if appID == "" {
 		log.Fatalln("An OpenWeather Map app ID is required. Please set the OPENWEATHER_MAP_APP_ID env var")
 	}
-	measurementProvider := &uv.OpenWeatherMap{Host: "https://api.openweathermap.org", AppID: appID}
+	openWeatherMap := &uv.OpenWeatherMap{Host: "https://api.openweathermap.org", AppID: appID}
+	dailyCallQuota := 1000
+	if quota := os.Getenv("OPENWEATHER_MAP_DAILY_CALLS"); quota != "" {
+		parsedQuota, parseError := strconv.Atoi(quota)
+		if parseError != nil {
+			log.Fatalln(fmt.Errorf("invalid OPENWEATHER_MAP_DAILY_CALLS env var: %w", parseError))
+		}
+		dailyCallQuota = parsedQuota
+	}
+	openWeatherMapQuota := uv.NewQuotaCounter("openweathermap", dailyCallQuota)
+	geohashPrecision := uv.DefaultGeohashPrecision
+	if precision := os.Getenv("GEOHASH_PRECISION"); precision != "" {
+		parsedPrecision, parseError := strconv.Atoi(precision)
+		if parseError != nil {
+			log.Fatalln(fmt.Errorf("invalid GEOHASH_PRECISION env var: %w", parseError))
+		}
+		if parsedPrecision < 1 || parsedPrecision > 12 {
+			log.Fatalln("GEOHASH_PRECISION must be between 1 and 12 characters")
+		}
+		geohashPrecision = parsedPrecision
+	}
+	measurementCellCache := uv.NewCellCache("measurement", geohashPrecision, parseDurationEnv("MEASUREMENT_CELL_TTL", "1m"))
+	var measurementProvider uv.MeasurementProvider = &uv.CorrectedProvider{Provider: &uv.SharedCellProvider{
+		Provider: &uv.BudgetedProvider{Provider: openWeatherMap, Counter: openWeatherMapQuota},
+		Cache:    measurementCellCache,
+	}}
+	forecastCacheTTL := 30 * time.Minute
+	forecastCellCache := uv.NewCellCache("forecast", geohashPrecision, forecastCacheTTL)
+	forecastProvider := &uv.CorrectedForecastProvider{Provider: uv.NewCachedForecastProvider(&uv.SharedCellForecastProvider{
+		Provider: &uv.BudgetedForecastProvider{Provider: openWeatherMap, Counter: openWeatherMapQuota},
+		Cache:    forecastCellCache,
+	}, forecastCacheTTL)}
+
+	metricsListenAddress := os.Getenv("METRICS_LISTEN_ADDRESS")
+	if metricsListenAddress != "" {
+		go func() {
+			log.Printf("Serving metrics at %s/debug/vars\n", metricsListenAddress)
+			log.Fatalln(http.ListenAndServe(metricsListenAddress, nil))
+		}()
+	}
+
+	measurementFile := os.Getenv("MEASUREMENT_FILE")
+	if measurementFile != "" {
+		fileFormat := uv.FileFormatCSV
+		if strings.HasSuffix(measurementFile, ".jsonl") {
+			fileFormat = uv.FileFormatJSONL
+		}
+		fileProvider := uv.NewFileProvider(measurementFile, fileFormat, 30*time.Minute)
+		if column := os.Getenv("MEASUREMENT_FILE_TIMESTAMP_COLUMN"); column != "" {
+			fileProvider.Columns.Timestamp = column
+		}
+		if column := os.Getenv("MEASUREMENT_FILE_LOCATION_COLUMN"); column != "" {
+			fileProvider.Columns.Location = column
+		}
+		if column := os.Getenv("MEASUREMENT_FILE_UV_COLUMN"); column != "" {
+			fileProvider.Columns.UV = column
+		}
+		if layout := os.Getenv("MEASUREMENT_FILE_TIMESTAMP_LAYOUT"); layout != "" {
+			fileProvider.Columns.TimestampLayout = layout
+		}
+		measurementProvider = &uv.FallbackProvider{Providers: []uv.MeasurementProvider{fileProvider, measurementProvider}}
+	}
+
+	stationListenAddress := os.Getenv("STATION_LISTEN_ADDRESS")
+	if stationListenAddress != "" {
+		stationMux := http.NewServeMux()
+		stationProviders := []uv.MeasurementProvider{}
+
+		ecowittStations := os.Getenv("ECOWITT_STATIONS")
+		if ecowittStations != "" {
+			locationsByPassKey, stationsError := parseStations(ecowittStations)
+			if stationsError != nil {
+				log.Fatalln(fmt.Errorf("invalid ECOWITT_STATIONS env var: %w", stationsError))
+			}
+			ecowittReceiver := uv.NewEcowittReceiver(locationsByPassKey, 15*time.Minute)
+			stationMux.Handle("/ecowitt", ecowittReceiver)
+			stationProviders = append(stationProviders, ecowittReceiver)
+		}
+
+		wundergroundStations := os.Getenv("WUNDERGROUND_STATIONS")
+		if wundergroundStations != "" {
+			locationsByCredentials, stationsError := parseStations(wundergroundStations)
+			if stationsError != nil {
+				log.Fatalln(fmt.Errorf("invalid WUNDERGROUND_STATIONS env var: %w", stationsError))
+			}
+			stations := map[string]*uv.WundergroundStation{}
+			for credentials, location := range locationsByCredentials {
+				idAndPassword := strings.SplitN(credentials, ":", 2)
+				if len(idAndPassword) != 2 {
+					log.Fatalf("invalid WUNDERGROUND_STATIONS env var: expected id:password but got %s\n", credentials)
+				}
+				stations[idAndPassword[0]] = &uv.WundergroundStation{Password: idAndPassword[1], Location: location}
+			}
+			wundergroundReceiver := uv.NewWundergroundReceiver(stations, 15*time.Minute)
+			stationMux.Handle(uv.WundergroundUploadPath, wundergroundReceiver)
+			stationProviders = append(stationProviders, wundergroundReceiver)
+		}
+
+		measurementProvider = &uv.FallbackProvider{Providers: append(stationProviders, measurementProvider)}
+
+		go func() {
+			log.Printf("Listening for weather station uploads on %s\n", stationListenAddress)
+			log.Fatalln(http.ListenAndServe(stationListenAddress, stationMux))
+		}()
+	}
 
 	consumerKey := os.Getenv("TWITTER_CONSUMER_KEY")
 	if consumerKey == "" {
@@ -54,8 +166,186 @@ func main() {
 		AccessSecret:   accessSecret,
 	}
 	measurementReporter := uv.NewTwitterMeasurementReporter(twitterAuth)
-	measurerAndReporter := uv.GetMeasureAndReportFunction(measurementProvider, measurementReporter)
-	measurementSettings := &uv.MeasurementSettings{ExitChan: exitChan, LoopInterval: 2 * time.Second, PollInterval: 2 * time.Minute}
+	messageReporters := []uv.MessageReporter{measurementReporter}
+	dailyLog := uv.NewDailyLog()
+	doseTracker := uv.NewDoseTracker()
+	recorders := []uv.MeasurementRecorder{dailyLog, doseTracker}
+	scheduledTasks := []uv.ScheduledTask{}
+
+	historyPath := os.Getenv("HISTORY_DB")
+	if historyPath != "" {
+		history, historyError := uv.OpenHistory(historyPath)
+		if historyError != nil {
+			log.Fatalln(historyError)
+		}
+		defer history.Close()
+		recorders = append(recorders, history)
+		retentionPolicy := &uv.RetentionPolicy{FullResolution: 90 * 24 * time.Hour, DownsampleInterval: 30 * time.Minute}
+		scheduledTasks = append(scheduledTasks,
+			&uv.HistoryRetentionTask{History: history, Policy: retentionPolicy},
+			&uv.MilestoneTask{Locations: uv.Locations, History: history, Reporters: messageReporters},
+		)
+
+		weeklyRecapDay := time.Sunday
+		if recapDay := os.Getenv("WEEKLY_RECAP_DAY"); recapDay != "" {
+			parsedDay, weekdayError := parseWeekday(recapDay)
+			if weekdayError != nil {
+				log.Fatalln(fmt.Errorf("invalid WEEKLY_RECAP_DAY env var: %w", weekdayError))
+			}
+			weeklyRecapDay = parsedDay
+		}
+		weeklyRecapAt := parseClockTimeEnv("WEEKLY_RECAP_TIME", "09:00")
+		monthlyRecapAt := parseClockTimeEnv("MONTHLY_RECAP_TIME", "09:00")
+		scheduledTasks = append(scheduledTasks,
+			&uv.RecapTask{Locations: uv.Locations, History: history, Reporters: messageReporters, Period: uv.RecapWeekly, At: weeklyRecapAt, Weekday: weeklyRecapDay},
+			&uv.RecapTask{Locations: uv.Locations, History: history, Reporters: messageReporters, Period: uv.RecapMonthly, At: monthlyRecapAt},
+		)
+	}
+	if vitaminDAlerts := os.Getenv("VITAMIN_D_ALERTS"); vitaminDAlerts != "" {
+		enabled, parseError := strconv.ParseBool(vitaminDAlerts)
+		if parseError != nil {
+			log.Fatalln(fmt.Errorf("invalid VITAMIN_D_ALERTS env var: %w", parseError))
+		}
+		if enabled {
+			uv.TelAvivTemplates.VitaminDAlert = uv.TelAvivVitaminDAlert
+		}
+	}
+	cardReporter := &uv.ChartCardReporter{Reporters: []uv.MediaReporter{measurementReporter}, Log: dailyLog, ForecastProvider: forecastProvider}
+	measurerAndReporter := uv.GetMeasureAndReportFunction(measurementProvider, cardReporter, recorders...)
+	nightSolarElevation := uv.DefaultNightSolarElevation
+	if elevation := os.Getenv("NIGHT_SOLAR_ELEVATION"); elevation != "" {
+		parsedElevation, parseError := strconv.ParseFloat(elevation, 64)
+		if parseError != nil {
+			log.Fatalln(fmt.Errorf("invalid NIGHT_SOLAR_ELEVATION env var: %w", parseError))
+		}
+		nightSolarElevation = parsedElevation
+	}
+	measurerAndReporter = uv.GetDaylightAwareFunction(measurerAndReporter, &uv.DaylightSettings{MinSolarElevation: nightSolarElevation})
+	adaptivePolling := &uv.AdaptivePolling{
+		MinInterval: parseDurationEnv("MIN_POLL_INTERVAL", "1m"),
+		MaxInterval: parseDurationEnv("MAX_POLL_INTERVAL", "15m"),
+	}
+	if adaptivePolling.MinInterval > adaptivePolling.MaxInterval {
+		log.Fatalln("MIN_POLL_INTERVAL must not be longer than MAX_POLL_INTERVAL")
+	}
+	// The forecasts are cached, so every location fetches at most one forecast
+	// per TTL
+	budgetPlanner := &uv.BudgetPlanner{
+		DailyCalls:        dailyCallQuota,
+		ReservedCalls:     int(24 * time.Hour / forecastCacheTTL),
+		MinInterval:       adaptivePolling.MinInterval,
+		MaxInterval:       adaptivePolling.MaxInterval,
+		MinSolarElevation: nightSolarElevation,
+	}
+	budgetPlan, budgetError := planLongestDay(budgetPlanner, uv.Locations, time.Now().Year())
+	if budgetError != nil {
+		log.Fatalln(fmt.Errorf("invalid polling configuration: %w", budgetError))
+	}
+	for _, locationBudget := range budgetPlan.Locations {
+		log.Printf("Budgeted %d calls over %s of daylight for %s\n", locationBudget.Calls, locationBudget.Location.DisplayName, locationBudget.Daylight)
+	}
+	if budgetPlan.Degraded {
+		log.Printf("Polling every %s instead of %s to stay within %d daily calls\n", budgetPlan.Interval, adaptivePolling.MinInterval, dailyCallQuota)
+		adaptivePolling.MinInterval = budgetPlan.Interval
+	}
+	measurementSettings := &uv.MeasurementSettings{ExitChan: exitChan, LoopInterval: 2 * time.Second, PollInterval: 2 * time.Minute, AdaptivePolling: adaptivePolling}
+
+	morningBriefingAt := parseClockTimeEnv("MORNING_BRIEFING_TIME", "07:00")
+	warningLeadTime := 30 * time.Minute
+	if leadTime := os.Getenv("ADVANCE_WARNING_LEAD_TIME"); leadTime != "" {
+		parsedLeadTime, durationError := time.ParseDuration(leadTime)
+		if durationError != nil {
+			log.Fatalln(fmt.Errorf("invalid ADVANCE_WARNING_LEAD_TIME env var: %w", durationError))
+		}
+		warningLeadTime = parsedLeadTime
+	}
+	doseThresholds := uv.DefaultDoseThresholds
+	if thresholds := os.Getenv("DOSE_THRESHOLDS"); thresholds != "" {
+		doseThresholds = []float64{}
+		for _, threshold := range strings.Split(thresholds, ",") {
+			parsedThreshold, parseError := strconv.ParseFloat(strings.TrimSpace(threshold), 64)
+			if parseError != nil {
+				log.Fatalln(fmt.Errorf("invalid DOSE_THRESHOLDS env var: %w", parseError))
+			}
+			doseThresholds = append(doseThresholds, parsedThreshold)
+		}
+	}
+	scheduledTasks = append(scheduledTasks,
+		&uv.MorningBriefingTask{Locations: uv.Locations, ForecastProvider: forecastProvider, Reporters: messageReporters, At: morningBriefingAt},
+		&uv.AdvanceWarningTask{Locations: uv.Locations, ForecastProvider: forecastProvider, Reporters: messageReporters, LeadTime: warningLeadTime},
+		&uv.EveningSummaryTask{Locations: uv.Locations, Log: dailyLog, Reporters: messageReporters, FallbackTime: uv.ClockTime{Hour: 19}},
+		&uv.DoseThresholdTask{Locations: uv.Locations, Tracker: doseTracker, Reporters: messageReporters, Thresholds: doseThresholds},
+	)
+	go uv.RunSchedule(scheduledTasks, &uv.ScheduleSettings{ExitChan: exitChan, LoopInterval: 30 * time.Second})
 
 	uv.MeasureAndReport(measurerAndReporter, measurementSettings)
 }
+
+// parseStations parses a comma separated list of station=location display name
+// pairs, e.g. "A1B2C3=Tel-Aviv,D4E5F6=Tel-Aviv".
+func parseStations(stations string) (map[string]*uv.Location, error) {
+	locationsByStation := map[string]*uv.Location{}
+	for _, station := range strings.Split(stations, ",") {
+		keyAndLocation := strings.SplitN(station, "=", 2)
+		if len(keyAndLocation) != 2 {
+			return nil, fmt.Errorf("expected station=location but got %s", station)
+		}
+		location, locationError := uv.FindLocation(strings.TrimSpace(keyAndLocation[1]))
+		if locationError != nil {
+			return nil, locationError
+		}
+		locationsByStation[strings.TrimSpace(keyAndLocation[0])] = location
+	}
+	return locationsByStation, nil
+}
+
+// parseClockTimeEnv parses the time of day in the env var, or defaultValue if
+// it is not set.
+func parseClockTimeEnv(name string, defaultValue string) uv.ClockTime {
+	value := os.Getenv(name)
+	if value == "" {
+		value = defaultValue
+	}
+	clockTime, clockTimeError := uv.ParseClockTime(value)
+	if clockTimeError != nil {
+		log.Fatalln(fmt.Errorf("invalid %s env var: %w", name, clockTimeError))
+	}
+	return clockTime
+}
+
+// planLongestDay plans the budget for the solstice with the most daylight
+// across the locations, which keeps the plan feasible all year round.
+func planLongestDay(planner *uv.BudgetPlanner, locations []*uv.Location, year int) (*uv.BudgetPlan, error) {
+	var longestPlan *uv.BudgetPlan
+	for _, solstice := range []time.Time{time.Date(year, time.June, 21, 12, 0, 0, 0, time.UTC), time.Date(year, time.December, 21, 12, 0, 0, 0, time.UTC)} {
+		plan, planError := planner.Plan(locations, solstice)
+		if planError != nil {
+			return nil, planError
+		}
+		if longestPlan == nil || plan.Interval > longestPlan.Interval {
+			longestPlan = plan
+		}
+	}
+	return longestPlan, nil
+}
+
+func parseDurationEnv(name string, defaultValue string) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		value = defaultValue
+	}
+	duration, durationError := time.ParseDuration(value)
+	if durationError != nil {
+		log.Fatalln(fmt.Errorf("invalid %s env var: %w", name, durationError))
+	}
+	return duration
+}
+
+func parseWeekday(value string) (time.Weekday, error) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(weekday.String(), value) {
+			return weekday, nil
+		}
+	}
+	return time.Sunday, fmt.Errorf("unknown weekday %s", value)
+}
@@ -6,4 +6,5 @@ require (
 	github.com/dghubble/go-twitter v0.0.0-20210609183100-2fdbf421508e
 	github.com/dghubble/oauth1 v0.7.0
 	github.com/stretchr/testify v1.4.0 // indirect
+	go.etcd.io/bbolt v1.3.6
 )
@@ -10,21 +10,81 @@ var AltertsByLocation = map[string]Alerts{
 }
 
 type Alerts interface {
-	Low(uvIndex float32) string
-	Moderate(uvIndex float32) string
-	High(uvIndex float32) string
+	Low(measurement *Measurement) string
+	Moderate(measurement *Measurement) string
+	High(measurement *Measurement) string
+}
+
+// AlertLevel is the level that a crossing alert is posted for. The alerts
+// predate the WHO categories and only post when IndexHasChanged sees the UV
+// index cross 3 or 8.
+type AlertLevel int
+
+const (
+	AlertLevelLow AlertLevel = iota
+	AlertLevelModerate
+	AlertLevelHigh
+)
+
+var AlertLevels = []AlertLevel{AlertLevelLow, AlertLevelModerate, AlertLevelHigh}
+
+var alertLevelThresholds = map[AlertLevel]float32{
+	AlertLevelLow:      0,
+	AlertLevelModerate: 3,
+	AlertLevelHigh:     8,
+}
+
+func AlertLevelOf(uvIndex float32) AlertLevel {
+	for i := len(AlertLevels) - 1; i > 0; i-- {
+		if uvIndex >= alertLevelThresholds[AlertLevels[i]] {
+			return AlertLevels[i]
+		}
+	}
+	return AlertLevelLow
+}
+
+// Threshold is the lowest UV index that is alerted at the level.
+func (level AlertLevel) Threshold() float32 {
+	return alertLevelThresholds[level]
+}
+
+func (level AlertLevel) String() string {
+	switch level {
+	case AlertLevelLow:
+		return "Low"
+	case AlertLevelModerate:
+		return "Moderate"
+	case AlertLevelHigh:
+		return "High"
+	}
+	return fmt.Sprintf("AlertLevel(%d)", int(level))
 }
 
 type TelAvivAlerts struct{}
 
-func (TelAvivAlerts) Low(uvIndex float32) string {
-	return fmt.Sprintf("The UV index in Tel-Aviv is %.1f. It's safe to go outside! 😎\n#uvindex #telaviv #uvbot_%d", uvIndex, time.Now().Unix())
+func (TelAvivAlerts) Low(measurement *Measurement) string {
+	return fmt.Sprintf("The UV index in Tel-Aviv is %.1f%s. It's safe to go outside! 😎\n#uvindex #telaviv #uvbot_%d", measurement.UVIndex, rawUVIndexNote(measurement), time.Now().Unix())
+}
+
+func (TelAvivAlerts) Moderate(measurement *Measurement) string {
+	return fmt.Sprintf("The UV Index in Tel-Aviv is %.1f%s. Seek shade and lather up on that sun screen! 🌞\n#uvindex #telaviv #uvbot_%d", measurement.UVIndex, rawUVIndexNote(measurement), time.Now().Unix())
+}
+
+func (TelAvivAlerts) High(measurement *Measurement) string {
+	return fmt.Sprintf("Hot dang! The UV Index in Tel-Aviv is %.1f%s. Stay indoors! 🔥\n#uvindex #telaviv #uvbot_%d", measurement.UVIndex, rawUVIndexNote(measurement), time.Now().Unix())
 }
 
-func (TelAvivAlerts) Moderate(uvIndex float32) string {
-	return fmt.Sprintf("The UV Index in Tel-Aviv is %.1f. Seek shade and lather up on that sun screen! 🌞\n#uvindex #telaviv #uvbot_%d", uvIndex, time.Now().Unix())
+// rawUVIndexNote mentions the provider's value when it was corrected for the
+// elevation and surface of the location.
+func rawUVIndexNote(measurement *Measurement) string {
+	return rawNote(measurement.UVIndex, measurement.RawUVIndex)
 }
 
-func (TelAvivAlerts) High(uvIndex float32) string {
-	return fmt.Sprintf("Hot dang! The UV Index in Tel-Aviv is %.1f. Stay indoors! 🔥\n#uvindex #telaviv #uvbot_%d", uvIndex, time.Now().Unix())
+// rawNote mentions the raw value of a corrected UV index, unless the two read
+// the same at the posted precision.
+func rawNote(uvIndex float32, rawUVIndex *float32) string {
+	if rawUVIndex == nil || fmt.Sprintf("%.1f", *rawUVIndex) == fmt.Sprintf("%.1f", uvIndex) {
+		return ""
+	}
+	return fmt.Sprintf(" (%.1f before elevation and surface correction)", *rawUVIndex)
 }
@@ -19,17 +19,26 @@ func TestTelAvivAlerts(t *testing.T) {
 	telAvivAlerts := &uv.TelAvivAlerts{}
 
 	expectedLow := "The UV index in Tel-Aviv is 1.1. It's safe to go outside! 😎\n#uvindex #telaviv #uvbot_"
-	if !strings.HasPrefix(telAvivAlerts.Low(1.1), expectedLow) {
-		t.Errorf("Expected %s to start with %s", telAvivAlerts.Low(1.1), expectedLow)
+	if !strings.HasPrefix(telAvivAlerts.Low(&uv.Measurement{UVIndex: 1.1}), expectedLow) {
+		t.Errorf("Expected %s to start with %s", telAvivAlerts.Low(&uv.Measurement{UVIndex: 1.1}), expectedLow)
 	}
 
 	expectedModerate := "The UV Index in Tel-Aviv is 2.1. Seek shade and lather up on that sun screen! 🌞\n#uvindex #telaviv #uvbot_"
-	if !strings.HasPrefix(telAvivAlerts.Moderate(2.1), expectedModerate) {
-		t.Errorf("Expected %s to start with %s", telAvivAlerts.Moderate(2.1), expectedModerate)
+	if !strings.HasPrefix(telAvivAlerts.Moderate(&uv.Measurement{UVIndex: 2.1}), expectedModerate) {
+		t.Errorf("Expected %s to start with %s", telAvivAlerts.Moderate(&uv.Measurement{UVIndex: 2.1}), expectedModerate)
 	}
 
 	expectedHigh := "Hot dang! The UV Index in Tel-Aviv is 3.2. Stay indoors! 🔥\n#uvindex #telaviv #uvbot_"
-	if !strings.HasPrefix(telAvivAlerts.High(3.21), expectedHigh) {
-		t.Errorf("Expected %s to start with %s", telAvivAlerts.High(3.21), expectedHigh)
+	if !strings.HasPrefix(telAvivAlerts.High(&uv.Measurement{UVIndex: 3.21}), expectedHigh) {
+		t.Errorf("Expected %s to start with %s", telAvivAlerts.High(&uv.Measurement{UVIndex: 3.21}), expectedHigh)
+	}
+}
+
+func TestAlertLevelOf(t *testing.T) {
+	cases := map[float32]uv.AlertLevel{0: uv.AlertLevelLow, 2.9: uv.AlertLevelLow, 3: uv.AlertLevelModerate, 6.5: uv.AlertLevelModerate, 8: uv.AlertLevelHigh, 12: uv.AlertLevelHigh}
+	for uvIndex, expected := range cases {
+		if level := uv.AlertLevelOf(uvIndex); level != expected {
+			t.Errorf("Expected UV index %.1f to be alerted as %s but got %s", uvIndex, expected, level)
+		}
 	}
 }
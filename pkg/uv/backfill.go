@@ -0,0 +1,200 @@
+package uv
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// HistoricalProvider returns the measurements of a location throughout the UTC
+// date of day.
+type HistoricalProvider interface {
+	History(locationToMeasure *Location, day time.Time) ([]*Measurement, error)
+}
+
+// OpenWeatherMapHistoryDays is how many days back the OneCall 2.5 timemachine
+// endpoint serves. Older dates need one of OpenWeatherMap's paid history
+// products.
+const OpenWeatherMapHistoryDays = 5
+
+var ErrBeyondHistory = errors.New("the provider has no history that old")
+
+// History calls the OneCall timemachine endpoint, which returns the hourly
+// values of the UTC date of day, for up to OpenWeatherMapHistoryDays ago.
+func (openweathermap *OpenWeatherMap) History(locationToMeasure *Location, day time.Time) ([]*Measurement, error) {
+	ocr := OneCallResponse{}
+	noon := time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, time.UTC)
+	callError := openweathermap.oneCall("/data/2.5/onecall/timemachine", locationToMeasure, map[string]string{"dt": fmt.Sprintf("%d", noon.Unix())}, &ocr)
+	if callError != nil {
+		return nil, callError
+	}
+	measurements := []*Measurement{}
+	for _, hour := range ocr.Hourly {
+		cloudCover := hour.Clouds
+		measurements = append(measurements, &Measurement{
+			ObservedAt: time.Unix(hour.DT, 0),
+			Source:     "openweathermap",
+			UVIndex:    hour.UVI,
+			CloudCover: &cloudCover,
+		})
+	}
+	return measurements, nil
+}
+
+var backfillBucket = []byte("backfill")
+
+// IsBackfilled returns whether the UTC date of day was already backfilled for
+// the location.
+func (history *History) IsBackfilled(location *Location, day time.Time) (bool, error) {
+	backfilled := false
+	viewError := history.db.View(func(tx *bolt.Tx) error {
+		backfill := tx.Bucket(backfillBucket)
+		if backfill == nil {
+			return nil
+		}
+		bucket := backfill.Bucket([]byte(location.DisplayName))
+		backfilled = bucket != nil && bucket.Get([]byte(day.UTC().Format("2006-01-02"))) != nil
+		return nil
+	})
+	return backfilled, viewError
+}
+
+// MarkBackfilled checkpoints the UTC date of day as backfilled for the location.
+func (history *History) MarkBackfilled(location *Location, day time.Time) error {
+	return history.db.Update(func(tx *bolt.Tx) error {
+		backfill, backfillError := tx.CreateBucketIfNotExists(backfillBucket)
+		if backfillError != nil {
+			return backfillError
+		}
+		bucket, bucketError := backfill.CreateBucketIfNotExists([]byte(location.DisplayName))
+		if bucketError != nil {
+			return bucketError
+		}
+		return bucket.Put([]byte(day.UTC().Format("2006-01-02")), []byte(time.Now().UTC().Format(time.RFC3339)))
+	})
+}
+
+// Backfiller populates the history of a location one UTC date at a time. Every
+// completed date is checkpointed in the history, so an interrupted backfill
+// resumes where it stopped.
+type Backfiller struct {
+	Provider HistoricalProvider
+	History  *History
+	// CallInterval is the minimum time between two calls to the provider
+	CallInterval time.Duration
+	// RateLimitBackoff is how long to wait after the provider rate limited a call
+	RateLimitBackoff time.Duration
+	MaxRetries       int
+	// HistoryDays is how many days back the provider serves, without a limit
+	// when zero
+	HistoryDays int
+	ExitChan    <-chan bool
+	Now         func() time.Time
+
+	lastCall time.Time
+}
+
+// CheckRange fails with ErrBeyondHistory when start is older than the
+// provider serves, so that no calls are spent on days that cannot be fetched.
+func (backfiller *Backfiller) CheckRange(start time.Time) error {
+	if backfiller.HistoryDays == 0 {
+		return nil
+	}
+	now := backfiller.now()
+	today := time.Date(now.UTC().Year(), now.UTC().Month(), now.UTC().Day(), 0, 0, 0, 0, time.UTC)
+	oldestDay := today.AddDate(0, 0, -backfiller.HistoryDays)
+	if start.Before(oldestDay) {
+		return fmt.Errorf("%w: %s is before %s, %d days ago", ErrBeyondHistory, start.Format("2006-01-02"), oldestDay.Format("2006-01-02"), backfiller.HistoryDays)
+	}
+	return nil
+}
+
+// Backfill stores the history of the location from start (inclusive) until
+// end (exclusive), corrected for the location like CorrectedProvider does. A
+// rate limited call is retried up to MaxRetries times. Days that have not ended
+// yet are stored but not checkpointed, so that a later run fetches the rest of
+// them.
+func (backfiller *Backfiller) Backfill(location *Location, start time.Time, end time.Time) error {
+	if rangeError := backfiller.CheckRange(start); rangeError != nil {
+		return rangeError
+	}
+	factor := CorrectionFactor(location)
+	firstDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	for day := firstDay; day.Before(end); day = day.AddDate(0, 0, 1) {
+		select {
+		case <-backfiller.ExitChan:
+			return errors.New("backfill was interrupted")
+		default:
+		}
+
+		backfilled, checkpointError := backfiller.History.IsBackfilled(location, day)
+		if checkpointError != nil {
+			return fmt.Errorf("failed to read the backfill checkpoint: %w", checkpointError)
+		}
+		if backfilled {
+			log.Printf("%s was already backfilled for %s\n", day.Format("2006-01-02"), location.DisplayName)
+			continue
+		}
+
+		measurements, historyError := backfiller.fetch(location, day)
+		if historyError != nil {
+			return fmt.Errorf("failed to get the history of %s on %s: %w", location.DisplayName, day.Format("2006-01-02"), historyError)
+		}
+		records := []*HistoryRecord{}
+		for _, measurement := range measurements {
+			if measurement.ObservedAt.Before(start) || !measurement.ObservedAt.Before(end) {
+				continue
+			}
+			record := &HistoryRecord{
+				Location:   location.DisplayName,
+				ObservedAt: measurement.ObservedAt,
+				Source:     measurement.Source,
+				UVIndex:    measurement.UVIndex,
+			}
+			if factor != 1 {
+				rawUVIndex := measurement.UVIndex
+				record.RawUVIndex = &rawUVIndex
+				record.UVIndex = rawUVIndex * factor
+			}
+			record.Category = CategoryOf(record.UVIndex)
+			records = append(records, record)
+		}
+		if storeError := backfiller.History.Store(records...); storeError != nil {
+			return storeError
+		}
+		if day.AddDate(0, 0, 1).After(backfiller.now()) {
+			log.Printf("Backfilled %d records of %s so far for %s\n", len(records), day.Format("2006-01-02"), location.DisplayName)
+			continue
+		}
+		if checkpointError := backfiller.History.MarkBackfilled(location, day); checkpointError != nil {
+			return fmt.Errorf("failed to write the backfill checkpoint: %w", checkpointError)
+		}
+		log.Printf("Backfilled %d records of %s for %s\n", len(records), day.Format("2006-01-02"), location.DisplayName)
+	}
+	return nil
+}
+
+func (backfiller *Backfiller) now() time.Time {
+	if backfiller.Now != nil {
+		return backfiller.Now()
+	}
+	return time.Now()
+}
+
+func (backfiller *Backfiller) fetch(location *Location, day time.Time) ([]*Measurement, error) {
+	for attempt := 0; ; attempt++ {
+		if wait := backfiller.CallInterval - time.Since(backfiller.lastCall); wait > 0 {
+			time.Sleep(wait)
+		}
+		backfiller.lastCall = time.Now()
+		measurements, historyError := backfiller.Provider.History(location, day)
+		if !errors.Is(historyError, ErrRateLimited) || attempt >= backfiller.MaxRetries {
+			return measurements, historyError
+		}
+		log.Printf("Rate limited, waiting %s before retrying\n", backfiller.RateLimitBackoff)
+		time.Sleep(backfiller.RateLimitBackoff)
+	}
+}
@@ -0,0 +1,228 @@
+package uv_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+type testHistoricalProvider struct {
+	RateLimitedCalls int
+	FailOnDay        string
+	RequestedDays    []string
+}
+
+func (t *testHistoricalProvider) History(locationToMeasure *uv.Location, day time.Time) ([]*uv.Measurement, error) {
+	if t.RateLimitedCalls > 0 {
+		t.RateLimitedCalls--
+		return nil, uv.ErrRateLimited
+	}
+	date := day.Format("2006-01-02")
+	if date == t.FailOnDay {
+		return nil, errors.New("something happened")
+	}
+	t.RequestedDays = append(t.RequestedDays, date)
+	measurements := []*uv.Measurement{}
+	for hour := 0; hour < 24; hour++ {
+		measurements = append(measurements, &uv.Measurement{ObservedAt: day.Add(time.Duration(hour) * time.Hour), Source: "test", UVIndex: float32(hour % 12)})
+	}
+	return measurements, nil
+}
+
+func TestOpenWeatherMap_History(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/data/2.5/onecall/timemachine" {
+			t.Errorf("Expected URL path %s but got %s", "/data/2.5/onecall/timemachine", r.URL.Path)
+		}
+		if r.URL.Query().Get("dt") != strconv.FormatInt(1624276800, 10) {
+			t.Errorf("Expected dt query param %d but got %s", 1624276800, r.URL.Query().Get("dt"))
+		}
+		json.NewEncoder(w).Encode(&uv.OneCallResponse{
+			Current: &uv.OneCallCurrent{DT: 1624276800, UVI: 10.1},
+			Hourly:  []*uv.OneCallHourly{{DT: 1624266000, UVI: 8.1, Clouds: 10}, {DT: 1624269600, UVI: 9.4}},
+		})
+	}))
+	defer server.Close()
+
+	openWeatherMap := &uv.OpenWeatherMap{Host: server.URL, AppID: "abcd"}
+	measurements, err := openWeatherMap.History(uv.Locations[0], time.Date(2021, time.June, 21, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(measurements) != 2 {
+		t.Fatalf("Expected %d measurements but got %d", 2, len(measurements))
+	}
+	if measurements[1].UVIndex != 9.4 || measurements[1].ObservedAt.Unix() != 1624269600 || measurements[1].Source != "openweathermap" {
+		t.Errorf("Unexpected measurement %+v", measurements[1])
+	}
+}
+
+func TestOpenWeatherMap_HistoryRateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	openWeatherMap := &uv.OpenWeatherMap{Host: server.URL, AppID: "abcd"}
+	_, err := openWeatherMap.History(uv.Locations[0], time.Date(2021, time.June, 21, 0, 0, 0, 0, time.UTC))
+	if !errors.Is(err, uv.ErrRateLimited) {
+		t.Errorf("Expected a rate limit error but got %v", err)
+	}
+}
+
+func TestBackfiller_Backfill(t *testing.T) {
+	history := openTestHistory(t)
+	provider := &testHistoricalProvider{RateLimitedCalls: 2}
+	backfiller := &uv.Backfiller{Provider: provider, History: history, MaxRetries: 3}
+	start := time.Date(2021, time.June, 20, 0, 0, 0, 0, time.UTC)
+
+	if err := backfiller.Backfill(uv.TelAviv, start, start.AddDate(0, 0, 2)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(provider.RequestedDays) != 2 {
+		t.Errorf("Expected %d requested days but got %v", 2, provider.RequestedDays)
+	}
+	records, _ := history.Range(uv.TelAviv, start, start.AddDate(0, 0, 2))
+	if len(records) != 48 {
+		t.Errorf("Expected %d records but got %d", 48, len(records))
+	}
+	if backfilled, _ := history.IsBackfilled(uv.TelAviv, start.AddDate(0, 0, 1)); !backfilled {
+		t.Error("Expected the second day to be checkpointed")
+	}
+	if records[0].RawUVIndex != nil {
+		t.Errorf("Expected no raw UV index without a correction but got %+v", records[0])
+	}
+}
+
+func TestBackfiller_BackfillCorrects(t *testing.T) {
+	history := openTestHistory(t)
+	mountain := &uv.Location{DisplayName: "mountain", Elevation: 1000}
+	backfiller := &uv.Backfiller{Provider: &testHistoricalProvider{}, History: history}
+	start := time.Date(2021, time.June, 20, 0, 0, 0, 0, time.UTC)
+
+	if err := backfiller.Backfill(mountain, start, start.AddDate(0, 0, 1)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	records, _ := history.Range(mountain, start.Add(10*time.Hour), start.Add(11*time.Hour))
+	if len(records) != 1 {
+		t.Fatalf("Expected %d record but got %d", 1, len(records))
+	}
+	if records[0].UVIndex != 11 || records[0].RawUVIndex == nil || *records[0].RawUVIndex != 10 || records[0].Category != uv.CategoryExtreme {
+		t.Errorf("Expected the record to be corrected from 10 to 11 but got %+v", records[0])
+	}
+}
+
+func TestBackfiller_BackfillResumes(t *testing.T) {
+	history := openTestHistory(t)
+	provider := &testHistoricalProvider{FailOnDay: "2021-06-21"}
+	backfiller := &uv.Backfiller{Provider: provider, History: history}
+	start := time.Date(2021, time.June, 20, 0, 0, 0, 0, time.UTC)
+
+	if err := backfiller.Backfill(uv.TelAviv, start, start.AddDate(0, 0, 3)); err == nil {
+		t.Error("Expected the backfill to fail")
+	}
+	if backfilled, _ := history.IsBackfilled(uv.TelAviv, start.AddDate(0, 0, 1)); backfilled {
+		t.Error("Expected the failed day not to be checkpointed")
+	}
+
+	provider.FailOnDay = ""
+	provider.RequestedDays = nil
+	if err := backfiller.Backfill(uv.TelAviv, start, start.AddDate(0, 0, 3)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(provider.RequestedDays) != 2 || provider.RequestedDays[0] != "2021-06-21" {
+		t.Errorf("Expected to resume from %s but requested %v", "2021-06-21", provider.RequestedDays)
+	}
+}
+
+func TestBackfiller_BackfillToday(t *testing.T) {
+	history := openTestHistory(t)
+	provider := &testHistoricalProvider{}
+	now := time.Date(2021, time.June, 21, 15, 0, 0, 0, time.UTC)
+	backfiller := &uv.Backfiller{Provider: provider, History: history, Now: func() time.Time { return now }}
+	start := time.Date(2021, time.June, 20, 0, 0, 0, 0, time.UTC)
+
+	if err := backfiller.Backfill(uv.TelAviv, start, start.AddDate(0, 0, 2)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if backfilled, _ := history.IsBackfilled(uv.TelAviv, start); !backfilled {
+		t.Error("Expected the previous day to be checkpointed")
+	}
+	if backfilled, _ := history.IsBackfilled(uv.TelAviv, start.AddDate(0, 0, 1)); backfilled {
+		t.Error("Expected today not to be checkpointed before it ends")
+	}
+
+	provider.RequestedDays = nil
+	if err := backfiller.Backfill(uv.TelAviv, start, start.AddDate(0, 0, 2)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(provider.RequestedDays) != 1 || provider.RequestedDays[0] != "2021-06-21" {
+		t.Errorf("Expected today to be fetched again but requested %v", provider.RequestedDays)
+	}
+}
+
+func TestBackfiller_BackfillGivesUpWhenRateLimited(t *testing.T) {
+	history := openTestHistory(t)
+	provider := &testHistoricalProvider{RateLimitedCalls: 5}
+	backfiller := &uv.Backfiller{Provider: provider, History: history, MaxRetries: 1}
+	start := time.Date(2021, time.June, 20, 0, 0, 0, 0, time.UTC)
+
+	err := backfiller.Backfill(uv.TelAviv, start, start.AddDate(0, 0, 1))
+	if !errors.Is(err, uv.ErrRateLimited) {
+		t.Errorf("Expected a rate limit error but got %v", err)
+	}
+}
+
+func TestBackfiller_BackfillInterrupted(t *testing.T) {
+	exitChan := make(chan bool)
+	close(exitChan)
+	provider := &testHistoricalProvider{}
+	backfiller := &uv.Backfiller{Provider: provider, History: openTestHistory(t), ExitChan: exitChan}
+	start := time.Date(2021, time.June, 20, 0, 0, 0, 0, time.UTC)
+
+	if err := backfiller.Backfill(uv.TelAviv, start, start.AddDate(0, 0, 1)); err == nil {
+		t.Error("Expected the backfill to be interrupted")
+	}
+	if len(provider.RequestedDays) != 0 {
+		t.Errorf("Expected no requests but got %v", provider.RequestedDays)
+	}
+}
+
+func TestBackfiller_BackfillBeyondHistory(t *testing.T) {
+	provider := &testHistoricalProvider{}
+	now := time.Date(2021, time.June, 25, 15, 0, 0, 0, time.UTC)
+	backfiller := &uv.Backfiller{Provider: provider, History: openTestHistory(t), HistoryDays: uv.OpenWeatherMapHistoryDays, Now: func() time.Time { return now }}
+
+	start := time.Date(2021, time.June, 19, 0, 0, 0, 0, time.UTC)
+	if err := backfiller.Backfill(uv.TelAviv, start, start.AddDate(0, 0, 2)); !errors.Is(err, uv.ErrBeyondHistory) {
+		t.Errorf("Expected %v but got %v", uv.ErrBeyondHistory, err)
+	}
+	if len(provider.RequestedDays) != 0 {
+		t.Errorf("Expected no calls for a range beyond the history but requested %v", provider.RequestedDays)
+	}
+
+	start = start.AddDate(0, 0, 1)
+	if err := backfiller.Backfill(uv.TelAviv, start, start.AddDate(0, 0, 2)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestBackfiller_BackfillCountsQuota(t *testing.T) {
+	counter := uv.NewQuotaCounter("backfill-test", 1)
+	provider := &uv.BudgetedHistoricalProvider{Provider: &testHistoricalProvider{}, Counter: counter}
+	backfiller := &uv.Backfiller{Provider: provider, History: openTestHistory(t)}
+	start := time.Date(2021, time.June, 20, 0, 0, 0, 0, time.UTC)
+
+	if err := backfiller.Backfill(uv.TelAviv, start, start.AddDate(0, 0, 2)); !errors.Is(err, uv.ErrQuotaExhausted) {
+		t.Errorf("Expected %v but got %v", uv.ErrQuotaExhausted, err)
+	}
+	if counter.Used() != 1 {
+		t.Errorf("Expected the backfill to spend %d call but it spent %d", 1, counter.Used())
+	}
+}
@@ -0,0 +1,141 @@
+package uv
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// ProtectionThreshold is the UV index from which sun protection is needed.
+const ProtectionThreshold float32 = 3.0
+
+// MorningBriefing is the forecast of a single day, posted every morning.
+// Times are in the location's time zone.
+type MorningBriefing struct {
+	Location        *Location
+	Date            time.Time
+	PeakUVIndex     float32
+	PeakRawUVIndex  *float32
+	PeakTime        time.Time
+	NeedsProtection bool
+	ProtectionStart time.Time
+	ProtectionEnd   time.Time
+}
+
+// NewMorningBriefing summarizes the hourly forecast of the local date of day.
+// The protection window is interpolated between the hourly values.
+func NewMorningBriefing(location *Location, forecast *Forecast, day time.Time) (*MorningBriefing, error) {
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	hourly := forecast.HourlyBetween(midnight, midnight.AddDate(0, 0, 1))
+	if len(hourly) == 0 {
+		return nil, fmt.Errorf("the forecast of %s has no hourly values for %s", location.DisplayName, midnight.Format("2006-01-02"))
+	}
+
+	briefing := &MorningBriefing{Location: location, Date: midnight}
+	lastProtectedHour := -1
+	for i, hour := range hourly {
+		if i == 0 || hour.UVIndex > briefing.PeakUVIndex {
+			briefing.PeakUVIndex = hour.UVIndex
+			briefing.PeakRawUVIndex = hour.RawUVIndex
+			briefing.PeakTime = hour.Time.In(day.Location())
+		}
+		if hour.UVIndex < ProtectionThreshold {
+			continue
+		}
+		if !briefing.NeedsProtection {
+			briefing.NeedsProtection = true
+			briefing.ProtectionStart = hour.Time.In(day.Location())
+			if i > 0 {
+				briefing.ProtectionStart = crossingTime(hourly[i-1], hour, ProtectionThreshold).In(day.Location())
+			}
+		}
+		lastProtectedHour = i
+	}
+	if briefing.NeedsProtection {
+		briefing.ProtectionEnd = hourly[lastProtectedHour].Time.In(day.Location())
+		if lastProtectedHour+1 < len(hourly) {
+			briefing.ProtectionEnd = crossingTime(hourly[lastProtectedHour], hourly[lastProtectedHour+1], ProtectionThreshold).In(day.Location())
+		}
+	}
+	return briefing, nil
+}
+
+// crossingTime linearly interpolates the time between two consecutive hourly
+// forecasts at which the UV index reaches threshold.
+func crossingTime(before *HourlyForecast, after *HourlyForecast, threshold float32) time.Time {
+	if after.UVIndex == before.UVIndex {
+		return after.Time
+	}
+	fraction := float64(threshold-before.UVIndex) / float64(after.UVIndex-before.UVIndex)
+	offset := time.Duration(fraction * float64(after.Time.Sub(before.Time)))
+	return before.Time.Add(offset).Round(time.Minute)
+}
+
+// MorningBriefingTask posts the day's forecast of every location at a local
+// time of day to all of the reporters.
+type MorningBriefingTask struct {
+	Locations        []*Location
+	ForecastProvider ForecastProvider
+	Reporters        []MessageReporter
+	At               ClockTime
+
+	trigger dailyTrigger
+}
+
+func (task *MorningBriefingTask) Run(now time.Time) error {
+	for _, location := range task.Locations {
+		if briefingError := task.brief(location, now); briefingError != nil {
+			log.Println(fmt.Errorf("failed to post the morning briefing of %s: %w", location.DisplayName, briefingError))
+		}
+	}
+	return nil
+}
+
+func (task *MorningBriefingTask) brief(location *Location, now time.Time) error {
+	timeZone, timeZoneError := GetLocation(location.IANA)
+	if timeZoneError != nil {
+		return timeZoneError
+	}
+	localNow := now.In(timeZone)
+	at := task.At.On(localNow)
+	if !task.trigger.due(location, at, localNow) {
+		return nil
+	}
+
+	forecast, forecastError := task.ForecastProvider.Forecast(location)
+	if forecastError != nil {
+		return fmt.Errorf("failed to get the forecast: %w", forecastError)
+	}
+	briefing, briefingError := NewMorningBriefing(location, forecast, localNow)
+	if briefingError != nil {
+		return briefingError
+	}
+	templates, templatesError := getTemplates(location)
+	if templatesError != nil {
+		return templatesError
+	}
+	message, renderError := RenderTemplate("morning briefing", templates.MorningBriefing, briefing)
+	if renderError != nil {
+		return renderError
+	}
+
+	task.trigger.fired(location, at)
+	return reportMessage(task.Reporters, location, message)
+}
+
+// reportMessage sends the message to all of the reporters, even if some of
+// them fail.
+func reportMessage(reporters []MessageReporter, location *Location, message string) error {
+	failures := 0
+	var lastError error
+	for _, reporter := range reporters {
+		if reportError := reporter.ReportMessage(location, message); reportError != nil {
+			failures++
+			lastError = reportError
+		}
+	}
+	if lastError != nil {
+		return fmt.Errorf("%d of %d reporters failed to report: %w", failures, len(reporters), lastError)
+	}
+	return nil
+}
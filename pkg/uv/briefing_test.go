@@ -0,0 +1,110 @@
+package uv_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+type testMessageReporter struct {
+	FailOnLocation map[string]bool
+	Messages       []string
+}
+
+func (t *testMessageReporter) ReportMessage(locationToReport *uv.Location, message string) error {
+	if t.FailOnLocation[locationToReport.DisplayName] {
+		return errors.New("something happened")
+	}
+	t.Messages = append(t.Messages, message)
+	return nil
+}
+
+// hourlyForecast returns a forecast starting at start with one value per hour
+func hourlyForecast(start time.Time, uvIndices ...float32) *uv.Forecast {
+	forecast := &uv.Forecast{}
+	for i, uvIndex := range uvIndices {
+		forecast.Hourly = append(forecast.Hourly, &uv.HourlyForecast{Time: start.Add(time.Duration(i) * time.Hour), UVIndex: uvIndex})
+	}
+	return forecast
+}
+
+func TestNewMorningBriefing(t *testing.T) {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	day := time.Date(2021, time.June, 21, 7, 0, 0, 0, jerusalem)
+	forecast := hourlyForecast(time.Date(2021, time.June, 21, 6, 0, 0, 0, jerusalem), 0.5, 1.5, 4.5, 7, 9.8, 10.1, 9, 7, 5, 2.6, 1, 0)
+
+	briefing, err := uv.NewMorningBriefing(uv.TelAviv, forecast, day)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if briefing.PeakUVIndex != 10.1 {
+		t.Errorf("Expected peak %.1f but got %.1f", 10.1, briefing.PeakUVIndex)
+	}
+	if briefing.PeakTime.Format("15:04") != "11:00" {
+		t.Errorf("Expected peak time %s but got %s", "11:00", briefing.PeakTime.Format("15:04"))
+	}
+	if !briefing.NeedsProtection {
+		t.Error("Expected protection to be needed")
+	}
+	if briefing.ProtectionStart.Format("15:04") != "07:30" {
+		t.Errorf("Expected protection to start at %s but got %s", "07:30", briefing.ProtectionStart.Format("15:04"))
+	}
+	if briefing.ProtectionEnd.Format("15:04") != "14:50" {
+		t.Errorf("Expected protection to end at %s but got %s", "14:50", briefing.ProtectionEnd.Format("15:04"))
+	}
+}
+
+func TestNewMorningBriefing_NoProtection(t *testing.T) {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	day := time.Date(2021, time.December, 21, 7, 0, 0, 0, jerusalem)
+	forecast := hourlyForecast(time.Date(2021, time.December, 21, 7, 0, 0, 0, jerusalem), 0.2, 1, 2.1, 2.5, 2, 1)
+
+	briefing, err := uv.NewMorningBriefing(uv.TelAviv, forecast, day)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if briefing.NeedsProtection {
+		t.Error("Expected protection not to be needed")
+	}
+
+	if _, err := uv.NewMorningBriefing(uv.TelAviv, forecast, day.AddDate(0, 0, 1)); err == nil {
+		t.Error("Expected an error for a day without a forecast")
+	}
+}
+
+func TestMorningBriefingTask(t *testing.T) {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	forecast := hourlyForecast(time.Date(2021, time.June, 21, 6, 0, 0, 0, jerusalem), 0.5, 1.5, 4.5, 7, 9.8, 10.1, 9, 7, 5, 2.6, 1, 0)
+	forecastProvider := &testForecastProvider{ForecastForLocation: map[string]*uv.Forecast{uv.TelAviv.DisplayName: forecast}}
+	reporter := &testMessageReporter{}
+	failingReporter := &testMessageReporter{FailOnLocation: map[string]bool{uv.TelAviv.DisplayName: true}}
+	task := &uv.MorningBriefingTask{
+		Locations:        []*uv.Location{uv.TelAviv},
+		ForecastProvider: forecastProvider,
+		Reporters:        []uv.MessageReporter{failingReporter, reporter},
+		At:               uv.ClockTime{Hour: 7},
+	}
+
+	task.Run(time.Date(2021, time.June, 21, 6, 59, 0, 0, jerusalem))
+	if len(reporter.Messages) != 0 {
+		t.Error("Expected no briefing before the configured time")
+	}
+
+	task.Run(time.Date(2021, time.June, 21, 7, 0, 30, 0, jerusalem))
+	task.Run(time.Date(2021, time.June, 21, 7, 1, 0, 0, jerusalem))
+	if len(reporter.Messages) != 1 {
+		t.Fatalf("Expected a single briefing but got %d", len(reporter.Messages))
+	}
+	expected := "Good morning Tel-Aviv! ☀️ UV will peak at 10.1 around 11:00. Protect yourself between 07:30 and 14:50, fair skin burns in ~15 min at the peak. Solar noon is at 12:42.\n#uvindex #telaviv #uvbot_"
+	if !strings.HasPrefix(reporter.Messages[0], expected) {
+		t.Errorf("Expected %s to start with %s", reporter.Messages[0], expected)
+	}
+
+	task.Run(time.Date(2021, time.June, 22, 9, 0, 0, 0, jerusalem))
+	if len(reporter.Messages) != 1 {
+		t.Error("Expected no briefing long after the configured time")
+	}
+}
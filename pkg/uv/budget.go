@@ -0,0 +1,236 @@
+package uv
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrBudgetExceeded = errors.New("the locations need more calls than the daily quota allows")
+
+var ErrQuotaExhausted = errors.New("the daily call quota is exhausted")
+
+// quotaMetrics are published at /debug/vars by the expvar package.
+var quotaMetrics = expvar.NewMap("quota")
+
+// daylightSampleInterval is the resolution of the daylight window of a
+// location.
+const daylightSampleInterval = 10 * time.Minute
+
+// BudgetPlanner plans the polling of the locations within a provider's daily
+// call quota. Locations are only polled while the sun is above
+// MinSolarElevation, so the calls are spread over their daylight windows.
+type BudgetPlanner struct {
+	DailyCalls int
+	// ReservedCalls are the calls that every location makes each day besides
+	// polling, like forecasts
+	ReservedCalls int
+	MinInterval   time.Duration
+	// MaxInterval is the longest acceptable poll interval, without a limit when
+	// zero
+	MaxInterval       time.Duration
+	MinSolarElevation float64
+}
+
+type LocationBudget struct {
+	Location *Location
+	Daylight time.Duration
+	Calls    int
+}
+
+// BudgetPlan polls every location at Interval during its daylight window.
+// Degraded plans poll less often than the planner's MinInterval to stay
+// within the quota.
+type BudgetPlan struct {
+	Day       time.Time
+	Interval  time.Duration
+	Calls     int
+	Degraded  bool
+	Locations []*LocationBudget
+}
+
+// Plan returns the shortest poll interval, no shorter than MinInterval, that
+// keeps the calls of the locations on day within the quota. It fails with
+// ErrBudgetExceeded when even MaxInterval needs more calls than the quota.
+func (planner *BudgetPlanner) Plan(locations []*Location, day time.Time) (*BudgetPlan, error) {
+	plan := &BudgetPlan{Day: day, Interval: planner.MinInterval}
+	var totalDaylight time.Duration
+	for _, location := range locations {
+		daylight, daylightError := daylightDuration(location, day, planner.MinSolarElevation)
+		if daylightError != nil {
+			return nil, daylightError
+		}
+		totalDaylight += daylight
+		plan.Locations = append(plan.Locations, &LocationBudget{Location: location, Daylight: daylight})
+	}
+	available := planner.DailyCalls - planner.ReservedCalls*len(locations)
+	if available < 0 {
+		return nil, fmt.Errorf("%w: %d locations reserve %d calls of %d", ErrBudgetExceeded, len(locations), planner.ReservedCalls*len(locations), planner.DailyCalls)
+	}
+	if available > 0 && totalDaylight > 0 {
+		if interval := (totalDaylight / time.Duration(available)).Truncate(time.Minute); interval > plan.Interval {
+			plan.Interval = interval
+		}
+	}
+	if plan.Interval < time.Minute {
+		plan.Interval = time.Minute
+	}
+	for planner.callsAt(plan.Locations, plan.Interval) > planner.DailyCalls && plan.Interval < 24*time.Hour {
+		plan.Interval += time.Minute
+	}
+	maxInterval := planner.MaxInterval
+	if maxInterval == 0 {
+		maxInterval = 24 * time.Hour
+	}
+	if calls := planner.callsAt(plan.Locations, plan.Interval); calls > planner.DailyCalls || plan.Interval > maxInterval {
+		return nil, fmt.Errorf("%w: polling %d locations every %s needs %d calls of %d", ErrBudgetExceeded, len(locations), maxInterval, planner.callsAt(plan.Locations, maxInterval), planner.DailyCalls)
+	}
+	plan.Calls = planner.ReservedCalls * len(locations)
+	for _, locationBudget := range plan.Locations {
+		locationBudget.Calls = callsDuring(locationBudget.Daylight, plan.Interval)
+		plan.Calls += locationBudget.Calls
+	}
+	plan.Degraded = plan.Interval > planner.MinInterval
+	return plan, nil
+}
+
+func (planner *BudgetPlanner) callsAt(locationBudgets []*LocationBudget, interval time.Duration) int {
+	calls := planner.ReservedCalls * len(locationBudgets)
+	for _, locationBudget := range locationBudgets {
+		calls += callsDuring(locationBudget.Daylight, interval)
+	}
+	return calls
+}
+
+func callsDuring(daylight time.Duration, interval time.Duration) int {
+	if daylight <= 0 {
+		return 0
+	}
+	return int((daylight + interval - 1) / interval)
+}
+
+// daylightDuration is how long the sun is above minSolarElevation on the local
+// date of day.
+func daylightDuration(location *Location, day time.Time, minSolarElevation float64) (time.Duration, error) {
+	latitude, longitude, coordinatesError := location.Coordinates()
+	if coordinatesError != nil {
+		return 0, coordinatesError
+	}
+	timeZone, timeZoneError := GetLocation(location.IANA)
+	if timeZoneError != nil {
+		return 0, timeZoneError
+	}
+	localDay := day.In(timeZone)
+	start := time.Date(localDay.Year(), localDay.Month(), localDay.Day(), 0, 0, 0, 0, timeZone)
+	end := start.AddDate(0, 0, 1)
+	var daylight time.Duration
+	for t := start; t.Before(end); t = t.Add(daylightSampleInterval) {
+		if SolarElevation(latitude, longitude, t.Add(daylightSampleInterval/2)) >= minSolarElevation {
+			daylight += daylightSampleInterval
+		}
+	}
+	return daylight, nil
+}
+
+// QuotaCounter counts the calls to a provider against its daily quota, which
+// resets at midnight UTC, and publishes the used and remaining calls as
+// metrics.
+type QuotaCounter struct {
+	Name       string
+	DailyCalls int
+	Now        func() time.Time
+	mutex      sync.Mutex
+	day        string
+	used       int
+}
+
+func NewQuotaCounter(name string, dailyCalls int) *QuotaCounter {
+	counter := &QuotaCounter{Name: name, DailyCalls: dailyCalls}
+	quotaMetrics.Set(name+"_daily", expvar.Func(func() interface{} { return counter.DailyCalls }))
+	quotaMetrics.Set(name+"_used", expvar.Func(func() interface{} { return counter.Used() }))
+	quotaMetrics.Set(name+"_remaining", expvar.Func(func() interface{} { return counter.Remaining() }))
+	return counter
+}
+
+// Take counts a call, and fails with ErrQuotaExhausted when the quota of the
+// day is used up.
+func (counter *QuotaCounter) Take() error {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	counter.resetOnNewDay()
+	if counter.used >= counter.DailyCalls {
+		return fmt.Errorf("%w: %s made %d calls today", ErrQuotaExhausted, counter.Name, counter.used)
+	}
+	counter.used++
+	return nil
+}
+
+func (counter *QuotaCounter) Used() int {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	counter.resetOnNewDay()
+	return counter.used
+}
+
+func (counter *QuotaCounter) Remaining() int {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	counter.resetOnNewDay()
+	return counter.DailyCalls - counter.used
+}
+
+func (counter *QuotaCounter) resetOnNewDay() {
+	now := time.Now()
+	if counter.Now != nil {
+		now = counter.Now()
+	}
+	day := now.UTC().Format("2006-01-02")
+	if day != counter.day {
+		counter.day = day
+		counter.used = 0
+	}
+}
+
+// BudgetedProvider counts the measurements of a provider against its quota,
+// and stops calling the provider once the quota is exhausted.
+type BudgetedProvider struct {
+	Provider MeasurementProvider
+	Counter  *QuotaCounter
+}
+
+func (budgetedProvider *BudgetedProvider) Measure(locationToMeasure *Location) (*Measurement, error) {
+	if takeError := budgetedProvider.Counter.Take(); takeError != nil {
+		return nil, takeError
+	}
+	return budgetedProvider.Provider.Measure(locationToMeasure)
+}
+
+// BudgetedForecastProvider counts the forecasts of a provider against its
+// quota, and stops calling the provider once the quota is exhausted.
+type BudgetedForecastProvider struct {
+	Provider ForecastProvider
+	Counter  *QuotaCounter
+}
+
+func (budgetedProvider *BudgetedForecastProvider) Forecast(locationToForecast *Location) (*Forecast, error) {
+	if takeError := budgetedProvider.Counter.Take(); takeError != nil {
+		return nil, takeError
+	}
+	return budgetedProvider.Provider.Forecast(locationToForecast)
+}
+
+// BudgetedHistoricalProvider counts the history calls of a provider against
+// its quota, and stops calling the provider once the quota is exhausted.
+type BudgetedHistoricalProvider struct {
+	Provider HistoricalProvider
+	Counter  *QuotaCounter
+}
+
+func (budgetedProvider *BudgetedHistoricalProvider) History(locationToMeasure *Location, day time.Time) ([]*Measurement, error) {
+	if takeError := budgetedProvider.Counter.Take(); takeError != nil {
+		return nil, takeError
+	}
+	return budgetedProvider.Provider.History(locationToMeasure, day)
+}
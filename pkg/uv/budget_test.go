@@ -0,0 +1,90 @@
+package uv_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func TestBudgetPlannerPlan(t *testing.T) {
+	planner := &uv.BudgetPlanner{DailyCalls: 1000, ReservedCalls: 48, MinInterval: time.Minute, MaxInterval: 15 * time.Minute, MinSolarElevation: 2}
+	solstice := time.Date(2021, time.June, 21, 12, 0, 0, 0, time.UTC)
+
+	plan, err := planner.Plan([]*uv.Location{uv.TelAviv}, solstice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Degraded || plan.Interval != time.Minute {
+		t.Errorf("Expected a single location to be polled every minute but got %s", plan.Interval)
+	}
+	daylight := plan.Locations[0].Daylight
+	if daylight < 13*time.Hour || daylight > 14*time.Hour {
+		t.Errorf("Expected Tel-Aviv to have 13 to 14 hours of daylight above 2 degrees on the solstice but got %s", daylight)
+	}
+	if plan.Calls > planner.DailyCalls {
+		t.Errorf("Expected the plan to stay within %d calls but it makes %d", planner.DailyCalls, plan.Calls)
+	}
+
+	locations := []*uv.Location{}
+	for i := 0; i < 5; i++ {
+		locations = append(locations, &uv.Location{DisplayName: "budget-test", IANA: uv.TelAviv.IANA, Latitude: uv.TelAviv.Latitude, Longitude: uv.TelAviv.Longitude})
+	}
+	plan, err = planner.Plan(locations, solstice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Degraded || plan.Interval <= time.Minute {
+		t.Errorf("Expected five locations to be polled less often than every minute but got %s", plan.Interval)
+	}
+	if plan.Calls > planner.DailyCalls {
+		t.Errorf("Expected the degraded plan to stay within %d calls but it makes %d", planner.DailyCalls, plan.Calls)
+	}
+	shorterInterval := plan.Interval - time.Minute
+	calls := planner.ReservedCalls * len(locations)
+	for _, locationBudget := range plan.Locations {
+		calls += int((locationBudget.Daylight + shorterInterval - 1) / shorterInterval)
+	}
+	if calls <= planner.DailyCalls {
+		t.Errorf("Expected %s to be the shortest interval within the quota but %s makes %d calls", plan.Interval, shorterInterval, calls)
+	}
+}
+
+func TestBudgetPlannerPlan_Exceeded(t *testing.T) {
+	planner := &uv.BudgetPlanner{DailyCalls: 100, MinInterval: time.Minute, MaxInterval: 5 * time.Minute, MinSolarElevation: 2}
+	_, err := planner.Plan([]*uv.Location{uv.TelAviv}, time.Date(2021, time.June, 21, 12, 0, 0, 0, time.UTC))
+	if !errors.Is(err, uv.ErrBudgetExceeded) {
+		t.Errorf("Expected the budget to be exceeded but got %v", err)
+	}
+
+	planner = &uv.BudgetPlanner{DailyCalls: 100, ReservedCalls: 101, MinInterval: time.Minute}
+	_, err = planner.Plan([]*uv.Location{uv.TelAviv}, time.Date(2021, time.June, 21, 12, 0, 0, 0, time.UTC))
+	if !errors.Is(err, uv.ErrBudgetExceeded) {
+		t.Errorf("Expected the reserved calls to exceed the budget but got %v", err)
+	}
+}
+
+func TestBudgetedProvider(t *testing.T) {
+	now := time.Date(2021, 6, 21, 23, 0, 0, 0, time.UTC)
+	counter := uv.NewQuotaCounter("budget-test", 2)
+	counter.Now = func() time.Time { return now }
+	provider := &uv.BudgetedProvider{Provider: &testMeasurementProvider{}, Counter: counter}
+
+	for i := 0; i < 2; i++ {
+		if _, err := provider.Measure(uv.TelAviv); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if counter.Remaining() != 0 {
+		t.Errorf("Expected no remaining calls but got %d", counter.Remaining())
+	}
+	if _, err := provider.Measure(uv.TelAviv); !errors.Is(err, uv.ErrQuotaExhausted) {
+		t.Errorf("Expected the quota to be exhausted but got %v", err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if counter.Remaining() != 2 || counter.Used() != 0 {
+		t.Errorf("Expected the quota to reset at midnight UTC but %d calls remain", counter.Remaining())
+	}
+}
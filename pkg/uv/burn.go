@@ -0,0 +1,134 @@
+package uv
+
+import (
+	"fmt"
+	"time"
+)
+
+// SkinType is a Fitzpatrick skin phototype.
+type SkinType int
+
+const (
+	SkinTypeI SkinType = iota + 1
+	SkinTypeII
+	SkinTypeIII
+	SkinTypeIV
+	SkinTypeV
+	SkinTypeVI
+)
+
+var SkinTypes = []SkinType{SkinTypeI, SkinTypeII, SkinTypeIII, SkinTypeIV, SkinTypeV, SkinTypeVI}
+
+// minimalErythemalDoses are the typical doses in SED that redden unprotected
+// skin of each type.
+var minimalErythemalDoses = map[SkinType]float64{
+	SkinTypeI:   2,
+	SkinTypeII:  2.5,
+	SkinTypeIII: 3.5,
+	SkinTypeIV:  4.5,
+	SkinTypeV:   6,
+	SkinTypeVI:  10,
+}
+
+func (skinType SkinType) String() string {
+	switch skinType {
+	case SkinTypeI:
+		return "I"
+	case SkinTypeII:
+		return "II"
+	case SkinTypeIII:
+		return "III"
+	case SkinTypeIV:
+		return "IV"
+	case SkinTypeV:
+		return "V"
+	case SkinTypeVI:
+		return "VI"
+	}
+	return fmt.Sprintf("SkinType(%d)", int(skinType))
+}
+
+// Description is how the skin type is commonly referred to.
+func (skinType SkinType) Description() string {
+	switch skinType {
+	case SkinTypeI:
+		return "very fair skin"
+	case SkinTypeII:
+		return "fair skin"
+	case SkinTypeIII:
+		return "medium skin"
+	case SkinTypeIV:
+		return "olive skin"
+	case SkinTypeV:
+		return "brown skin"
+	case SkinTypeVI:
+		return "dark skin"
+	}
+	return skinType.String()
+}
+
+// MinimalErythemalDoseSED is the typical dose that reddens unprotected skin of
+// the type, or zero for an unknown type.
+func (skinType SkinType) MinimalErythemalDoseSED() float64 {
+	return minimalErythemalDoses[skinType]
+}
+
+// BurnTime estimates how long skin of the type can stay in a constant UV index
+// before it burns. An SPF above 1 multiplies the time, which assumes that the
+// sunscreen was applied as thickly as it was tested. The time is zero when the
+// UV index or the skin type cannot burn.
+func BurnTime(skinType SkinType, uvIndex float32, spf float32) time.Duration {
+	if uvIndex <= 0 || skinType.MinimalErythemalDoseSED() == 0 {
+		return 0
+	}
+	if spf < 1 {
+		spf = 1
+	}
+	seconds := skinType.MinimalErythemalDoseSED() * JoulesPerSED * float64(spf) / (float64(uvIndex) * ErythemalIrradiancePerUVIndex)
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// BurnTimes estimates the burn time of every skin type.
+func BurnTimes(uvIndex float32, spf float32) map[SkinType]time.Duration {
+	burnTimes := map[SkinType]time.Duration{}
+	for _, skinType := range SkinTypes {
+		burnTimes[skinType] = BurnTime(skinType, uvIndex, spf)
+	}
+	return burnTimes
+}
+
+// BurnTimeGuidance is the data of the high UV alert variant, which tells how
+// soon skin burns rather than to stay indoors.
+type BurnTimeGuidance struct {
+	Location    *Location
+	Measurement *Measurement
+	PostedAt    time.Time
+}
+
+func NewBurnTimeGuidance(location *Location, measurement *Measurement) *BurnTimeGuidance {
+	return &BurnTimeGuidance{Location: location, Measurement: measurement, PostedAt: time.Now()}
+}
+
+// BurnTime is the time that skin of the type, given as 1 to 6, takes to burn
+// in the measured UV index, with an optional SPF.
+func (guidance *BurnTimeGuidance) BurnTime(skinType int, spf ...float32) time.Duration {
+	if len(spf) > 0 {
+		return BurnTime(SkinType(skinType), guidance.Measurement.UVIndex, spf[0])
+	}
+	return BurnTime(SkinType(skinType), guidance.Measurement.UVIndex, 1)
+}
+
+// approximateDuration rounds a burn time to 5 minutes, e.g. "~15 min".
+func approximateDuration(duration time.Duration) string {
+	if duration <= 0 {
+		return "never"
+	}
+	duration = duration.Round(5 * time.Minute)
+	if duration < 5*time.Minute {
+		duration = 5 * time.Minute
+	}
+	if duration < time.Hour {
+		return fmt.Sprintf("~%d min", int(duration.Minutes()))
+	}
+	return fmt.Sprintf("~%dh%02dm", int(duration.Hours()), int(duration.Minutes())%60)
+}
@@ -0,0 +1,81 @@
+package uv_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func TestBurnTime(t *testing.T) {
+	tests := []struct {
+		skinType uv.SkinType
+		uvIndex  float32
+		spf      float32
+		expected time.Duration
+	}{
+		{uv.SkinTypeI, 10, 1, 800 * time.Second},
+		{uv.SkinTypeII, 10, 0, 1000 * time.Second},
+		{uv.SkinTypeVI, 4, 1, 10000 * time.Second},
+		{uv.SkinTypeII, 10, 30, 30000 * time.Second},
+		{uv.SkinTypeIII, 0, 1, 0},
+		{uv.SkinType(7), 10, 1, 0},
+	}
+	for _, test := range tests {
+		if burnTime := uv.BurnTime(test.skinType, test.uvIndex, test.spf); burnTime.Round(time.Second) != test.expected {
+			t.Errorf("Expected skin type %s to burn after %s in UV %.1f with SPF %.0f but got %s", test.skinType, test.expected, test.uvIndex, test.spf, burnTime)
+		}
+	}
+}
+
+func TestBurnTimes(t *testing.T) {
+	burnTimes := uv.BurnTimes(8, 1)
+	if len(burnTimes) != 6 {
+		t.Fatalf("Expected burn times of %d skin types but got %d", 6, len(burnTimes))
+	}
+	for i := 1; i < len(uv.SkinTypes); i++ {
+		if burnTimes[uv.SkinTypes[i]] <= burnTimes[uv.SkinTypes[i-1]] {
+			t.Errorf("Expected skin type %s to burn later than %s", uv.SkinTypes[i], uv.SkinTypes[i-1])
+		}
+	}
+}
+
+func TestRenderTemplate_BurnTime(t *testing.T) {
+	data := struct{ UVIndex float32 }{UVIndex: 10}
+	text := "{{skin 1}} burns in {{approx (burntime 1 .UVIndex)}}, with SPF 30 in {{approx (burntime 1 .UVIndex 30)}}. {{approx (burntime 1 0)}}"
+	rendered, err := uv.RenderTemplate("test", text, data)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected := "very fair skin burns in ~15 min, with SPF 30 in ~6h40m. never"
+	if rendered != expected {
+		t.Errorf("Expected %s but got %s", expected, rendered)
+	}
+}
+
+func TestBurnTimeAlert(t *testing.T) {
+	location := &uv.Location{DisplayName: "burn-alert-test", IANA: "Asia/Jerusalem", Latitude: 32.1, Longitude: 34.85}
+	uv.AltertsByLocation[location.DisplayName] = uv.TelAvivAlerts{}
+	defer delete(uv.AltertsByLocation, location.DisplayName)
+	uv.TemplatesByLocation[location.DisplayName] = &uv.Templates{
+		BurnTimeAlert: "UV is {{uv .Measurement.UVIndex}}, {{skin 2}} burns in {{approx (.BurnTime 2)}} or {{approx (.BurnTime 2 30)}} with SPF 30",
+	}
+	defer delete(uv.TemplatesByLocation, location.DisplayName)
+
+	reporter := &testMediaReporter{}
+	cardReporter := &uv.ChartCardReporter{Reporters: []uv.MediaReporter{reporter}}
+	cardReporter.Report(location, &uv.Measurement{ObservedAt: time.Now(), UVIndex: 10})
+	cardReporter.Report(location, &uv.Measurement{ObservedAt: time.Now(), UVIndex: 4})
+	if len(reporter.Messages) != 2 {
+		t.Fatalf("Expected %d alerts but got %d", 2, len(reporter.Messages))
+	}
+
+	expected := "UV is 10.0, fair skin burns in ~15 min or ~8h20m with SPF 30"
+	if reporter.Messages[0] != expected {
+		t.Errorf("Expected %s but got %s", expected, reporter.Messages[0])
+	}
+	if !strings.HasPrefix(reporter.Messages[1], "The UV Index in Tel-Aviv is 4.0.") {
+		t.Errorf("Expected the regular Moderate alert but got %s", reporter.Messages[1])
+	}
+}
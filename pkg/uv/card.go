@@ -0,0 +1,302 @@
+package uv
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"math"
+	"strings"
+	"time"
+)
+
+// The size of a chart card, in the 1.91:1 ratio that social networks crop
+// link and image previews to.
+const (
+	cardWidth        = 800
+	cardHeight       = 418
+	cardMarginLeft   = 50
+	cardMarginRight  = 20
+	cardMarginTop    = 80
+	cardMarginBottom = 40
+)
+
+var (
+	cardBackground     = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	cardTextColor      = color.RGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xff}
+	cardObservedColor  = color.RGBA{R: 0x1f, G: 0x3a, B: 0x93, A: 0xff}
+	cardForecastColor  = color.RGBA{R: 0x88, G: 0x88, B: 0x88, A: 0xff}
+	categoryBandColors = map[Category]color.RGBA{
+		CategoryLow:      {R: 0xe3, G: 0xf4, B: 0xdc, A: 0xff},
+		CategoryModerate: {R: 0xff, G: 0xf6, B: 0xc2, A: 0xff},
+		CategoryHigh:     {R: 0xff, G: 0xe1, B: 0xbd, A: 0xff},
+		CategoryVeryHigh: {R: 0xfc, G: 0xd0, B: 0xd0, A: 0xff},
+		CategoryExtreme:  {R: 0xe9, G: 0xd6, B: 0xf2, A: 0xff},
+	}
+	categoryColors = map[Category]color.RGBA{
+		CategoryLow:      {R: 0x4e, G: 0xa7, B: 0x2e, A: 0xff},
+		CategoryModerate: {R: 0xe0, G: 0xb0, B: 0x00, A: 0xff},
+		CategoryHigh:     {R: 0xf1, G: 0x80, B: 0x00, A: 0xff},
+		CategoryVeryHigh: {R: 0xd8, G: 0x00, B: 0x1d, A: 0xff},
+		CategoryExtreme:  {R: 0x6b, G: 0x49, B: 0xc8, A: 0xff},
+	}
+)
+
+// Media is an image attached to a post.
+type Media struct {
+	PNG     []byte
+	AltText string
+}
+
+// MediaReporter posts messages with an attached image. A nil media posts the
+// message alone.
+type MediaReporter interface {
+	ReportMedia(locationToReport *Location, message string, media *Media) error
+}
+
+// ChartCard is the UV curve of a single day: the observed measurements, the
+// hourly forecast and the current reading.
+type ChartCard struct {
+	Location *Location
+	Date     time.Time
+	Observed []*Measurement
+	Forecast []*HourlyForecast
+	Current  *Measurement
+}
+
+// NewChartCard charts the local date of day. The forecast is optional and the
+// observed measurements must be sorted by observation time.
+func NewChartCard(location *Location, day time.Time, observed []*Measurement, forecast *Forecast, current *Measurement) *ChartCard {
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	card := &ChartCard{Location: location, Date: midnight, Observed: observed, Current: current}
+	if forecast != nil {
+		card.Forecast = forecast.HourlyBetween(midnight, midnight.AddDate(0, 0, 1))
+	}
+	return card
+}
+
+// span returns the hours of the day in which the UV index is above zero,
+// padded by an hour on each side, or 06:00 to 19:00 when it never is.
+func (card *ChartCard) span() (time.Time, time.Time) {
+	var first, last time.Time
+	include := func(t time.Time, uvIndex float32) {
+		if uvIndex <= 0 {
+			return
+		}
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if last.IsZero() || t.After(last) {
+			last = t
+		}
+	}
+	for _, measurement := range card.Observed {
+		include(measurement.ObservedAt, measurement.UVIndex)
+	}
+	for _, hour := range card.Forecast {
+		include(hour.Time, hour.UVIndex)
+	}
+	if first.IsZero() {
+		return card.Date.Add(6 * time.Hour), card.Date.Add(19 * time.Hour)
+	}
+	start := first.In(card.Date.Location()).Add(-time.Hour).Truncate(time.Hour)
+	end := last.In(card.Date.Location()).Add(2 * time.Hour).Truncate(time.Hour)
+	return start, end
+}
+
+func (card *ChartCard) maxUVIndex() float32 {
+	var maxUVIndex float32
+	for _, measurement := range card.Observed {
+		if measurement.UVIndex > maxUVIndex {
+			maxUVIndex = measurement.UVIndex
+		}
+	}
+	for _, hour := range card.Forecast {
+		if hour.UVIndex > maxUVIndex {
+			maxUVIndex = hour.UVIndex
+		}
+	}
+	return maxUVIndex
+}
+
+// RenderPNG draws the card with the category bands in the background, the
+// forecast as a dashed line, the observed curve on top of it and the current
+// reading as a dot in the colour of its category.
+func (card *ChartCard) RenderPNG() ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, cardWidth, cardHeight))
+	fillRect(img, 0, 0, cardWidth, cardHeight, cardBackground)
+
+	start, end := card.span()
+	yMax := float32(12)
+	if maxUVIndex := card.maxUVIndex(); maxUVIndex+1 > yMax {
+		yMax = float32(math.Ceil(float64(maxUVIndex + 1)))
+	}
+	plotWidth := cardWidth - cardMarginLeft - cardMarginRight
+	plotHeight := cardHeight - cardMarginTop - cardMarginBottom
+	x := func(t time.Time) int {
+		return cardMarginLeft + int(float64(plotWidth)*float64(t.Sub(start))/float64(end.Sub(start)))
+	}
+	y := func(uvIndex float32) int {
+		return cardMarginTop + plotHeight - int(float32(plotHeight)*uvIndex/yMax)
+	}
+
+	for i, category := range Categories {
+		top := yMax
+		if i+1 < len(Categories) && Categories[i+1].Threshold() < yMax {
+			top = Categories[i+1].Threshold()
+		}
+		if category.Threshold() >= yMax {
+			break
+		}
+		fillRect(img, cardMarginLeft, y(top), plotWidth, y(category.Threshold())-y(top), categoryBandColors[category])
+		if category != CategoryLow {
+			label := fmt.Sprintf("%d", int(category.Threshold()))
+			drawText(img, cardMarginLeft-8-textWidth(label, 2), y(category.Threshold())-7, label, 2, cardTextColor)
+		}
+	}
+
+	for hour := start; !hour.After(end); hour = hour.Add(time.Hour) {
+		fillRect(img, x(hour), cardMarginTop+plotHeight, 1, 6, cardTextColor)
+		if hour.Hour()%3 == 0 {
+			label := fmt.Sprintf("%d", hour.Hour())
+			drawText(img, x(hour)-textWidth(label, 2)/2, cardMarginTop+plotHeight+12, label, 2, cardTextColor)
+		}
+	}
+	fillRect(img, cardMarginLeft, cardMarginTop+plotHeight, plotWidth, 1, cardTextColor)
+
+	for i := 1; i < len(card.Forecast); i++ {
+		drawLine(img, x(card.Forecast[i-1].Time), y(card.Forecast[i-1].UVIndex), x(card.Forecast[i].Time), y(card.Forecast[i].UVIndex), 1, 8, cardForecastColor)
+	}
+	for i := 1; i < len(card.Observed); i++ {
+		drawLine(img, x(card.Observed[i-1].ObservedAt), y(card.Observed[i-1].UVIndex), x(card.Observed[i].ObservedAt), y(card.Observed[i].UVIndex), 2, 0, cardObservedColor)
+	}
+
+	title := strings.ToUpper(card.Location.DisplayName) + " " + card.Date.Format("2006-01-02")
+	drawText(img, cardWidth-cardMarginRight-textWidth(title, 2), 20, title, 2, cardTextColor)
+	if card.Current != nil {
+		category := CategoryOf(card.Current.UVIndex)
+		currentX, currentY := x(card.Current.ObservedAt), y(card.Current.UVIndex)
+		fillCircle(img, currentX, currentY, 9, cardBackground)
+		fillCircle(img, currentX, currentY, 7, categoryColors[category])
+		drawText(img, cardMarginLeft, 16, fmt.Sprintf("UV %.1f", card.Current.UVIndex), 5, categoryColors[category])
+		subtitle := fmt.Sprintf("%s AT %s", category, card.Current.ObservedAt.In(card.Date.Location()).Format("15:04"))
+		drawText(img, cardWidth-cardMarginRight-textWidth(subtitle, 2), 44, subtitle, 2, categoryColors[category])
+	}
+
+	var encoded bytes.Buffer
+	if encodeError := png.Encode(&encoded, img); encodeError != nil {
+		return nil, fmt.Errorf("failed to encode the chart card: %w", encodeError)
+	}
+	return encoded.Bytes(), nil
+}
+
+// AltText describes the card for readers who cannot see it.
+func (card *ChartCard) AltText() string {
+	sentences := []string{fmt.Sprintf("Chart of the UV index in %s on %s.", card.Location.DisplayName, card.Date.Format("January 2, 2006"))}
+	if card.Current != nil {
+		sentences = append(sentences, fmt.Sprintf("The UV index is %.1f, %s, at %s.", card.Current.UVIndex, CategoryOf(card.Current.UVIndex), card.Current.ObservedAt.In(card.Date.Location()).Format("15:04")))
+	}
+	if len(card.Observed) > 0 {
+		peak := card.Observed[0]
+		for _, measurement := range card.Observed {
+			if measurement.UVIndex > peak.UVIndex {
+				peak = measurement
+			}
+		}
+		sentences = append(sentences, fmt.Sprintf("The highest measurement so far was %.1f at %s.", peak.UVIndex, peak.ObservedAt.In(card.Date.Location()).Format("15:04")))
+	}
+	if len(card.Forecast) > 0 {
+		peak := card.Forecast[0]
+		for _, hour := range card.Forecast {
+			if hour.UVIndex > peak.UVIndex {
+				peak = hour
+			}
+		}
+		sentences = append(sentences, fmt.Sprintf("The forecast peaks at %.1f around %s.", peak.UVIndex, peak.Time.In(card.Date.Location()).Format("15:04")))
+	}
+	sentences = append(sentences, "Coloured bands mark the Low, Moderate, High, Very High and Extreme categories.")
+	return strings.Join(sentences, " ")
+}
+
+// drawLine draws a line of the given thickness. A dash length above zero draws
+// it dashed.
+func drawLine(img *image.RGBA, x0 int, y0 int, x1 int, y1 int, thickness int, dash int, lineColor color.Color) {
+	steps := int(math.Max(math.Abs(float64(x1-x0)), math.Abs(float64(y1-y0))))
+	if steps == 0 {
+		steps = 1
+	}
+	for step := 0; step <= steps; step++ {
+		if dash > 0 && (step/dash)%2 == 1 {
+			continue
+		}
+		px := x0 + (x1-x0)*step/steps
+		py := y0 + (y1-y0)*step/steps
+		fillRect(img, px-thickness/2, py-thickness/2, thickness, thickness, lineColor)
+	}
+}
+
+func fillCircle(img *image.RGBA, centerX int, centerY int, radius int, fillColor color.Color) {
+	for py := -radius; py <= radius; py++ {
+		for px := -radius; px <= radius; px++ {
+			if px*px+py*py <= radius*radius {
+				img.Set(centerX+px, centerY+py, fillColor)
+			}
+		}
+	}
+}
+
+// ChartCardReporter posts the regular alerts with a chart card of the day
+// attached. When the card cannot be drawn, the alert is posted without it.
+type ChartCardReporter struct {
+	Reporters        []MediaReporter
+	Log              *DailyLog
+	ForecastProvider ForecastProvider
+}
+
+func (cardReporter *ChartCardReporter) Report(locationToReport *Location, measurement *Measurement) error {
+	message := getAlert(locationToReport, measurement)
+	media, cardError := cardReporter.card(locationToReport, measurement)
+	if cardError != nil {
+		log.Println(fmt.Errorf("failed to draw the chart card of %s: %w", locationToReport.DisplayName, cardError))
+	}
+	failures := 0
+	var lastError error
+	for _, reporter := range cardReporter.Reporters {
+		if reportError := reporter.ReportMedia(locationToReport, message, media); reportError != nil {
+			failures++
+			lastError = reportError
+		}
+	}
+	if lastError != nil {
+		return fmt.Errorf("%d of %d reporters failed to report: %w", failures, len(cardReporter.Reporters), lastError)
+	}
+	return nil
+}
+
+func (cardReporter *ChartCardReporter) card(location *Location, measurement *Measurement) (*Media, error) {
+	timeZone, timeZoneError := GetLocation(location.IANA)
+	if timeZoneError != nil {
+		return nil, timeZoneError
+	}
+	day := measurement.ObservedAt.In(timeZone)
+	var observed []*Measurement
+	if cardReporter.Log != nil {
+		observed = cardReporter.Log.Measurements(location, day)
+	}
+	var forecast *Forecast
+	if cardReporter.ForecastProvider != nil {
+		var forecastError error
+		forecast, forecastError = cardReporter.ForecastProvider.Forecast(location)
+		if forecastError != nil {
+			log.Println(fmt.Errorf("failed to get the forecast of %s for the chart card: %w", location.DisplayName, forecastError))
+		}
+	}
+	card := NewChartCard(location, day, observed, forecast, measurement)
+	encoded, renderError := card.RenderPNG()
+	if renderError != nil {
+		return nil, renderError
+	}
+	return &Media{PNG: encoded, AltText: card.AltText()}, nil
+}
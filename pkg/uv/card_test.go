@@ -0,0 +1,113 @@
+package uv_test
+
+import (
+	"bytes"
+	"errors"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+type testMediaReporter struct {
+	FailOnLocation map[string]bool
+	Messages       []string
+	Media          []*uv.Media
+}
+
+func (t *testMediaReporter) ReportMedia(locationToReport *uv.Location, message string, media *uv.Media) error {
+	if t.FailOnLocation[locationToReport.DisplayName] {
+		return errors.New("something happened")
+	}
+	t.Messages = append(t.Messages, message)
+	t.Media = append(t.Media, media)
+	return nil
+}
+
+func testChartCard() *uv.ChartCard {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	day := time.Date(2021, time.June, 21, 0, 0, 0, 0, jerusalem)
+	observed := []*uv.Measurement{}
+	for i, uvIndex := range []float32{0.5, 1.8, 3.6, 5.5, 7.2, 8.6} {
+		observed = append(observed, &uv.Measurement{ObservedAt: day.Add(6*time.Hour + time.Duration(i)*time.Hour), UVIndex: uvIndex})
+	}
+	forecast := hourlyForecast(day.Add(5*time.Hour), 0, 0.4, 1.7, 3.5, 5.6, 7.4, 9, 9.8, 9.3, 7.6, 5.2, 3, 1.2, 0.3, 0)
+	return uv.NewChartCard(uv.TelAviv, day.Add(11*time.Hour), observed, forecast, observed[len(observed)-1])
+}
+
+func TestChartCard_RenderPNG(t *testing.T) {
+	encoded, err := testChartCard().RenderPNG()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatalf("Failed to decode the card: %v", err)
+	}
+	if img.Bounds().Dx() != 800 || img.Bounds().Dy() != 418 {
+		t.Errorf("Unexpected card size %v", img.Bounds())
+	}
+
+	colors := map[color.RGBA]bool{}
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			colors[color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)] = true
+		}
+	}
+	expectedColors := map[string]color.RGBA{
+		"Low band":            {R: 0xe3, G: 0xf4, B: 0xdc, A: 0xff},
+		"Extreme band":        {R: 0xe9, G: 0xd6, B: 0xf2, A: 0xff},
+		"Very High highlight": {R: 0xd8, G: 0x00, B: 0x1d, A: 0xff},
+		"observed curve":      {R: 0x1f, G: 0x3a, B: 0x93, A: 0xff},
+		"forecast curve":      {R: 0x88, G: 0x88, B: 0x88, A: 0xff},
+	}
+	for name, expected := range expectedColors {
+		if !colors[expected] {
+			t.Errorf("Expected the card to contain the %s", name)
+		}
+	}
+}
+
+func TestChartCard_RenderPNGWithoutData(t *testing.T) {
+	card := uv.NewChartCard(uv.TelAviv, time.Date(2021, time.December, 21, 0, 0, 0, 0, time.UTC), nil, nil, nil)
+	if _, err := card.RenderPNG(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestChartCard_AltText(t *testing.T) {
+	expected := "Chart of the UV index in Tel-Aviv on June 21, 2021. The UV index is 8.6, Very High, at 11:00. " +
+		"The highest measurement so far was 8.6 at 11:00. The forecast peaks at 9.8 around 12:00. " +
+		"Coloured bands mark the Low, Moderate, High, Very High and Extreme categories."
+	if altText := testChartCard().AltText(); altText != expected {
+		t.Errorf("Expected %s but got %s", expected, altText)
+	}
+}
+
+func TestChartCardReporter(t *testing.T) {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	dailyLog := uv.NewDailyLog()
+	recordDay(t, dailyLog, time.Date(2021, time.June, 21, 8, 0, 0, 0, jerusalem), time.Time{}, 3, 5, 7)
+	forecastProvider := &testForecastProvider{FailOnLocation: map[string]bool{uv.TelAviv.DisplayName: true}}
+	reporter := &testMediaReporter{}
+	failingReporter := &testMediaReporter{FailOnLocation: map[string]bool{uv.TelAviv.DisplayName: true}}
+	cardReporter := &uv.ChartCardReporter{Reporters: []uv.MediaReporter{reporter, failingReporter}, Log: dailyLog, ForecastProvider: forecastProvider}
+
+	measurement := &uv.Measurement{ObservedAt: time.Date(2021, time.June, 21, 10, 0, 0, 0, jerusalem), UVIndex: 7}
+	if err := cardReporter.Report(uv.TelAviv, measurement); err == nil || !strings.HasPrefix(err.Error(), "1 of 2 reporters failed") {
+		t.Errorf("Expected a single reporter to fail but got %v", err)
+	}
+	if len(reporter.Messages) != 1 || !strings.HasPrefix(reporter.Messages[0], "The UV Index in Tel-Aviv is 7.0") {
+		t.Fatalf("Expected the alert to be posted but got %v", reporter.Messages)
+	}
+	media := reporter.Media[0]
+	if media == nil || len(media.PNG) == 0 {
+		t.Fatal("Expected a chart card to be attached without a forecast")
+	}
+	if !strings.Contains(media.AltText, "The highest measurement so far was 7.0 at 09:00.") || strings.Contains(media.AltText, "forecast") {
+		t.Errorf("Unexpected alt text %s", media.AltText)
+	}
+}
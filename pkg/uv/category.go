@@ -0,0 +1,54 @@
+package uv
+
+import "fmt"
+
+// Category is the WHO exposure category of a UV index.
+type Category int
+
+const (
+	CategoryLow Category = iota
+	CategoryModerate
+	CategoryHigh
+	CategoryVeryHigh
+	CategoryExtreme
+)
+
+var Categories = []Category{CategoryLow, CategoryModerate, CategoryHigh, CategoryVeryHigh, CategoryExtreme}
+
+var categoryThresholds = map[Category]float32{
+	CategoryLow:      0,
+	CategoryModerate: 3,
+	CategoryHigh:     6,
+	CategoryVeryHigh: 8,
+	CategoryExtreme:  11,
+}
+
+func CategoryOf(uvIndex float32) Category {
+	for i := len(Categories) - 1; i > 0; i-- {
+		if uvIndex >= categoryThresholds[Categories[i]] {
+			return Categories[i]
+		}
+	}
+	return CategoryLow
+}
+
+// Threshold is the lowest UV index in the category.
+func (category Category) Threshold() float32 {
+	return categoryThresholds[category]
+}
+
+func (category Category) String() string {
+	switch category {
+	case CategoryLow:
+		return "Low"
+	case CategoryModerate:
+		return "Moderate"
+	case CategoryHigh:
+		return "High"
+	case CategoryVeryHigh:
+		return "Very High"
+	case CategoryExtreme:
+		return "Extreme"
+	}
+	return fmt.Sprintf("Category(%d)", int(category))
+}
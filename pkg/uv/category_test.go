@@ -0,0 +1,35 @@
+package uv_test
+
+import (
+	"testing"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func TestCategoryOf(t *testing.T) {
+	expectedCategories := map[float32]uv.Category{
+		0:    uv.CategoryLow,
+		2.9:  uv.CategoryLow,
+		3:    uv.CategoryModerate,
+		5.9:  uv.CategoryModerate,
+		6:    uv.CategoryHigh,
+		8:    uv.CategoryVeryHigh,
+		10.9: uv.CategoryVeryHigh,
+		11:   uv.CategoryExtreme,
+		14:   uv.CategoryExtreme,
+	}
+	for uvIndex, expectedCategory := range expectedCategories {
+		if category := uv.CategoryOf(uvIndex); category != expectedCategory {
+			t.Errorf("Expected UV index %.1f to be %s but got %s", uvIndex, expectedCategory, category)
+		}
+	}
+}
+
+func TestCategory(t *testing.T) {
+	if uv.CategoryVeryHigh.String() != "Very High" {
+		t.Errorf("Expected %s but got %s", "Very High", uv.CategoryVeryHigh)
+	}
+	if uv.CategoryHigh.Threshold() != 6 {
+		t.Errorf("Expected threshold %.1f but got %.1f", 6.0, uv.CategoryHigh.Threshold())
+	}
+}
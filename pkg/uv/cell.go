@@ -0,0 +1,242 @@
+package uv
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultGeohashPrecision is a geohash of about 5 km by 5 km, which is finer
+// than the grid of the gridded providers.
+const DefaultGeohashPrecision = 5
+
+// cellMetrics are published at /debug/vars by the expvar package.
+var cellMetrics = expvar.NewMap("cell_cache")
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Geohash encodes the coordinates as a geohash of precision characters.
+func Geohash(latitude float64, longitude float64, precision int) string {
+	latitudeRange := [2]float64{-90, 90}
+	longitudeRange := [2]float64{-180, 180}
+	geohash := make([]byte, 0, precision)
+	evenBit := true
+	bit := 0
+	character := 0
+	for len(geohash) < precision {
+		if evenBit {
+			middle := (longitudeRange[0] + longitudeRange[1]) / 2
+			if longitude >= middle {
+				character = character<<1 | 1
+				longitudeRange[0] = middle
+			} else {
+				character = character << 1
+				longitudeRange[1] = middle
+			}
+		} else {
+			middle := (latitudeRange[0] + latitudeRange[1]) / 2
+			if latitude >= middle {
+				character = character<<1 | 1
+				latitudeRange[0] = middle
+			} else {
+				character = character << 1
+				latitudeRange[1] = middle
+			}
+		}
+		evenBit = !evenBit
+		bit++
+		if bit == 5 {
+			geohash = append(geohash, geohashAlphabet[character])
+			bit = 0
+			character = 0
+		}
+	}
+	return string(geohash)
+}
+
+// CellCache shares the values fetched for a location with all the locations
+// in the same cell for TTL. Cells are geohashes of Precision characters, or a
+// latitude and longitude grid of GridDegrees when it is set. Expired cells are
+// pruned whenever a cell is fetched.
+type CellCache struct {
+	Name         string
+	Precision    int
+	GridDegrees  float64
+	TTL          time.Duration
+	Now          func() time.Time
+	mutex        sync.Mutex
+	cells        map[string]*cellEntry
+	fetchForCell map[string]*sync.Mutex
+	hits         int
+	misses       int
+}
+
+type cellEntry struct {
+	value     interface{}
+	fetchedAt time.Time
+}
+
+// NewCellCache creates a cache of geohash cells and publishes its hits, misses
+// and hit ratio as metrics under name.
+func NewCellCache(name string, precision int, ttl time.Duration) *CellCache {
+	cache := &CellCache{Name: name, Precision: precision, TTL: ttl}
+	cellMetrics.Set(name+"_hits", expvar.Func(func() interface{} { return cache.Hits() }))
+	cellMetrics.Set(name+"_misses", expvar.Func(func() interface{} { return cache.Misses() }))
+	cellMetrics.Set(name+"_hit_ratio", expvar.Func(func() interface{} { return cache.HitRatio() }))
+	cellMetrics.Set(name+"_cells", expvar.Func(func() interface{} { return cache.Cells() }))
+	return cache
+}
+
+// Cell returns the key of the cell that the location falls in.
+func (cache *CellCache) Cell(location *Location) (string, error) {
+	latitude, longitude, coordinatesError := location.Coordinates()
+	if coordinatesError != nil {
+		return "", coordinatesError
+	}
+	if cache.GridDegrees > 0 {
+		return fmt.Sprintf("%d:%d", int(math.Floor(latitude/cache.GridDegrees)), int(math.Floor(longitude/cache.GridDegrees))), nil
+	}
+	precision := cache.Precision
+	if precision == 0 {
+		precision = DefaultGeohashPrecision
+	}
+	return Geohash(latitude, longitude, precision), nil
+}
+
+// get returns the value of the location's cell, and calls fetch when the cell
+// has no value younger than TTL. Only the lock of the cell is held while
+// fetching, so a slow cell does not block the others.
+func (cache *CellCache) get(location *Location, fetch func() (interface{}, error)) (interface{}, error) {
+	cell, cellError := cache.Cell(location)
+	if cellError != nil {
+		return nil, cellError
+	}
+
+	fetchMutex := cache.fetchMutex(cell)
+	fetchMutex.Lock()
+	defer fetchMutex.Unlock()
+
+	now := time.Now()
+	if cache.Now != nil {
+		now = cache.Now()
+	}
+	cache.mutex.Lock()
+	entry, found := cache.cells[cell]
+	if found && now.Sub(entry.fetchedAt) < cache.TTL {
+		cache.hits++
+		cache.mutex.Unlock()
+		return entry.value, nil
+	}
+	cache.misses++
+	cache.mutex.Unlock()
+
+	value, fetchError := fetch()
+	if fetchError != nil {
+		return nil, fetchError
+	}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if cache.cells == nil {
+		cache.cells = map[string]*cellEntry{}
+	}
+	for key, cached := range cache.cells {
+		if now.Sub(cached.fetchedAt) >= cache.TTL {
+			delete(cache.cells, key)
+		}
+	}
+	cache.cells[cell] = &cellEntry{value: value, fetchedAt: now}
+	return value, nil
+}
+
+func (cache *CellCache) fetchMutex(cell string) *sync.Mutex {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if cache.fetchForCell == nil {
+		cache.fetchForCell = map[string]*sync.Mutex{}
+	}
+	fetchMutex, found := cache.fetchForCell[cell]
+	if !found {
+		fetchMutex = &sync.Mutex{}
+		cache.fetchForCell[cell] = fetchMutex
+	}
+	return fetchMutex
+}
+
+// Cells is the number of cells that hold a value, including expired ones that
+// were not pruned yet.
+func (cache *CellCache) Cells() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return len(cache.cells)
+}
+
+func (cache *CellCache) Hits() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.hits
+}
+
+func (cache *CellCache) Misses() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.misses
+}
+
+// HitRatio is the fraction of the lookups that were shared with another
+// location, zero before the first lookup.
+func (cache *CellCache) HitRatio() float64 {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if cache.hits+cache.misses == 0 {
+		return 0
+	}
+	return float64(cache.hits) / float64(cache.hits+cache.misses)
+}
+
+// SharedCellProvider makes one call to a gridded provider for all the
+// locations in the same cell. Each location gets its own copy of the
+// measurement, so wrappers like CorrectedProvider can change it.
+type SharedCellProvider struct {
+	Provider MeasurementProvider
+	Cache    *CellCache
+}
+
+func (sharedProvider *SharedCellProvider) Measure(locationToMeasure *Location) (*Measurement, error) {
+	value, measurementError := sharedProvider.Cache.get(locationToMeasure, func() (interface{}, error) {
+		return sharedProvider.Provider.Measure(locationToMeasure)
+	})
+	if measurementError != nil {
+		return nil, measurementError
+	}
+	measurement, ok := value.(*Measurement)
+	if !ok {
+		return nil, errors.New("cell cache holds a value that isn't a measurement")
+	}
+	shared := *measurement
+	return &shared, nil
+}
+
+// SharedCellForecastProvider makes one forecast call to a gridded provider for
+// all the locations in the same cell.
+type SharedCellForecastProvider struct {
+	Provider ForecastProvider
+	Cache    *CellCache
+}
+
+func (sharedProvider *SharedCellForecastProvider) Forecast(locationToForecast *Location) (*Forecast, error) {
+	value, forecastError := sharedProvider.Cache.get(locationToForecast, func() (interface{}, error) {
+		return sharedProvider.Provider.Forecast(locationToForecast)
+	})
+	if forecastError != nil {
+		return nil, forecastError
+	}
+	forecast, ok := value.(*Forecast)
+	if !ok {
+		return nil, errors.New("cell cache holds a value that isn't a forecast")
+	}
+	shared := *forecast
+	return &shared, nil
+}
@@ -0,0 +1,103 @@
+package uv_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func TestGeohash(t *testing.T) {
+	cases := []struct {
+		latitude  float64
+		longitude float64
+		precision int
+		expected  string
+	}{
+		{57.64911, 10.40744, 11, "u4pruydqqvj"},
+		{42.6, -5.6, 5, "ezs42"},
+		{32.109333, 34.855499, 5, "sv8y9"},
+	}
+	for _, testCase := range cases {
+		if geohash := uv.Geohash(testCase.latitude, testCase.longitude, testCase.precision); geohash != testCase.expected {
+			t.Errorf("Expected the geohash of %f,%f to be %s but got %s", testCase.latitude, testCase.longitude, testCase.expected, geohash)
+		}
+	}
+}
+
+func TestCellCacheCell(t *testing.T) {
+	cache := &uv.CellCache{GridDegrees: 0.25}
+	cell, err := cache.Cell(&uv.Location{DisplayName: "cell-test", Latitude: 32.109333, Longitude: -34.855499})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cell != "128:-140" {
+		t.Errorf("Expected the grid cell to be 128:-140 but got %s", cell)
+	}
+}
+
+func TestSharedCellProvider(t *testing.T) {
+	now := time.Date(2021, 6, 21, 9, 0, 0, 0, time.UTC)
+	cache := uv.NewCellCache("cell-test", 5, time.Minute)
+	cache.Now = func() time.Time { return now }
+	measurementProvider := &testMeasurementProvider{MeasurementForLocation: map[string]float32{"north": 5, "south": 7}}
+	provider := &uv.CorrectedProvider{Provider: &uv.SharedCellProvider{Provider: measurementProvider, Cache: cache}}
+
+	north := &uv.Location{DisplayName: "north", Latitude: 32.1093, Longitude: 34.8555}
+	nearby := &uv.Location{DisplayName: "nearby", Latitude: 32.1090, Longitude: 34.8560, Elevation: 1000}
+	south := &uv.Location{DisplayName: "south", Latitude: 31.2518, Longitude: 34.7913}
+
+	for _, location := range []*uv.Location{north, nearby, south} {
+		if _, err := provider.Measure(location); err != nil {
+			t.Fatal(err)
+		}
+	}
+	measurement, err := provider.Measure(north)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if measurement.UVIndex != 5 {
+		t.Errorf("Expected the shared measurement not to be changed by the correction of another location but got %f", measurement.UVIndex)
+	}
+	if len(measurementProvider.MeasuredLocations) != 2 || measurementProvider.MeasuredLocations[1] != "south" {
+		t.Errorf("Expected one call per cell but the provider measured %v", measurementProvider.MeasuredLocations)
+	}
+	if cache.Hits() != 2 || cache.Misses() != 2 || cache.HitRatio() != 0.5 {
+		t.Errorf("Expected 2 hits and 2 misses but got %d hits and %d misses", cache.Hits(), cache.Misses())
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := provider.Measure(nearby); err != nil {
+		t.Fatal(err)
+	}
+	if len(measurementProvider.MeasuredLocations) != 3 || measurementProvider.MeasuredLocations[2] != "nearby" {
+		t.Errorf("Expected the cell to be measured again after the TTL but the provider measured %v", measurementProvider.MeasuredLocations)
+	}
+	if cache.Cells() != 1 {
+		t.Errorf("Expected the expired cell of south to be pruned but the cache holds %d cells", cache.Cells())
+	}
+}
+
+func TestSharedCellForecastProvider_SlowCell(t *testing.T) {
+	provider := &blockingForecastProvider{Release: make(chan bool), Shared: &uv.Forecast{}}
+	sharedProvider := &uv.SharedCellForecastProvider{Provider: provider, Cache: uv.NewCellCache("slow-cell-test", 5, time.Hour)}
+
+	slowDone := make(chan bool)
+	go func() {
+		sharedProvider.Forecast(&uv.Location{DisplayName: "slow", Latitude: 32.1093, Longitude: 34.8555})
+		slowDone <- true
+	}()
+
+	fastDone := make(chan bool)
+	go func() {
+		sharedProvider.Forecast(&uv.Location{DisplayName: "fast", Latitude: 31.2518, Longitude: 34.7913})
+		fastDone <- true
+	}()
+	select {
+	case <-fastDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the forecast of another cell not to wait for the slow cell")
+	}
+	close(provider.Release)
+	<-slowDone
+}
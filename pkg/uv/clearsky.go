@@ -0,0 +1,101 @@
+package uv
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// ClearSkyModel computes the clear-sky UV index from first principles. It needs
+// no network, which makes it useful for offline testing, as a last-resort
+// fallback and for sanity-checking values returned by remote providers.
+//
+// The UV index is estimated with the parametrization by Madronich (2007):
+//
+//	UVI = 12.5 * cos(SZA)^2.42 * (ozone / 300 DU)^-1.23
+//
+// corrected for the Earth-Sun distance and for the elevation of the location,
+// at roughly 10% more UV per 1000 m as published by the WHO.
+type ClearSkyModel struct {
+	// TotalOzone is the total ozone column in Dobson units. When left unset the
+	// climatological value from ClimatologicalOzone is used.
+	TotalOzone float64
+	// Tolerance is the fraction by which a measured value may exceed the
+	// clear-sky estimate before CheckPlausible rejects it.
+	Tolerance float64
+	Now       func() time.Time
+}
+
+func (model *ClearSkyModel) Measure(locationToMeasure *Location) (*Measurement, error) {
+	latitude, longitude, coordinatesError := locationToMeasure.Coordinates()
+	if coordinatesError != nil {
+		return nil, coordinatesError
+	}
+	now := time.Now()
+	if model.Now != nil {
+		now = model.Now()
+	}
+	ozone := model.TotalOzone
+	if ozone == 0 {
+		ozone = ClimatologicalOzone(latitude, longitude, now)
+	}
+	clearSkyIndex := float32(ClearSkyUVIndex(latitude, longitude, locationToMeasure.Elevation, ozone, now))
+	totalOzone := float32(ozone)
+	return &Measurement{
+		ObservedAt:      now,
+		Source:          "clear-sky model",
+		UVIndex:         clearSkyIndex,
+		ClearSkyUVIndex: &clearSkyIndex,
+		Ozone:           &totalOzone,
+	}, nil
+}
+
+// CheckPlausible returns an error if the UV index reported for the location is
+// higher than what the sky could produce without any clouds.
+func (model *ClearSkyModel) CheckPlausible(location *Location, uvIndex float32) error {
+	clearSkyMeasurement, measurementError := model.Measure(location)
+	if measurementError != nil {
+		return fmt.Errorf("failed to compute clear-sky UV index for %s: %w", location.DisplayName, measurementError)
+	}
+	tolerance := model.Tolerance
+	if tolerance == 0 {
+		tolerance = 0.25
+	}
+	clearSkyIndex := clearSkyMeasurement.UVIndex
+	// Allow an absolute margin as well so that readings around sunrise and
+	// sunset are not rejected because the estimate is close to zero.
+	if float64(uvIndex) > float64(clearSkyIndex)*(1+tolerance)+1 {
+		return fmt.Errorf("UV index %.1f in %s is implausibly higher than the clear-sky estimate of %.1f", uvIndex, location.DisplayName, clearSkyIndex)
+	}
+	return nil
+}
+
+func ClearSkyUVIndex(latitude float64, longitude float64, elevation float64, ozone float64, t time.Time) float64 {
+	zenith := SolarZenithAngle(latitude, longitude, t)
+	cosZenith := math.Cos(degreesToRadians(zenith))
+	if cosZenith <= 0 || ozone <= 0 {
+		return 0
+	}
+	uvIndex := 12.5 * math.Pow(cosZenith, 2.42) * math.Pow(ozone/300, -1.23)
+	uvIndex *= EarthSunDistanceFactor(t)
+	uvIndex *= elevationFactor(elevation)
+	return uvIndex
+}
+
+// ClimatologicalOzone approximates the total ozone column in Dobson units using
+// the empirical model by van Heuklon (1979).
+func ClimatologicalOzone(latitude float64, longitude float64, t time.Time) float64 {
+	dayOfYear := float64(t.UTC().YearDay())
+	var a, beta, c, f, g, h, i float64
+	if latitude >= 0 {
+		a, beta, c, f, g, h = 150, 1.28, 40, -30, 20, 3
+		if longitude > 0 {
+			i = 20
+		}
+	} else {
+		a, beta, c, f, g, h, i = 100, 1.5, 30, 152.625, 20, 2, -75
+	}
+	seasonal := c * math.Sin(degreesToRadians(0.9865*(dayOfYear+f)))
+	longitudinal := g * math.Sin(degreesToRadians(h*(longitude+i)))
+	return 235 + (a+seasonal+longitudinal)*math.Pow(math.Sin(degreesToRadians(beta*latitude)), 2)
+}
@@ -0,0 +1,84 @@
+package uv_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func TestClearSkyUVIndex(t *testing.T) {
+	summerNoon := time.Date(2021, time.June, 21, 9, 40, 0, 0, time.UTC)
+	uvIndex := uv.ClearSkyUVIndex(32.1, 34.85, 0, 300, summerNoon)
+	if uvIndex < 10.5 || uvIndex > 12.5 {
+		t.Errorf("Expected a summer noon UV index in Tel-Aviv of about 11 but got %.2f", uvIndex)
+	}
+
+	atElevation := uv.ClearSkyUVIndex(32.1, 34.85, 2000, 300, summerNoon)
+	if atElevation <= uvIndex {
+		t.Errorf("Expected the UV index at elevation (%.2f) to be higher than at sea level (%.2f)", atElevation, uvIndex)
+	}
+
+	lessOzone := uv.ClearSkyUVIndex(32.1, 34.85, 0, 250, summerNoon)
+	if lessOzone <= uvIndex {
+		t.Errorf("Expected the UV index with less ozone (%.2f) to be higher than with more (%.2f)", lessOzone, uvIndex)
+	}
+
+	night := uv.ClearSkyUVIndex(32.1, 34.85, 0, 300, time.Date(2021, time.June, 21, 22, 0, 0, 0, time.UTC))
+	if night != 0 {
+		t.Errorf("Expected a UV index of 0 at night but got %.2f", night)
+	}
+}
+
+func TestClimatologicalOzone(t *testing.T) {
+	spring := uv.ClimatologicalOzone(32.1, 34.85, time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC))
+	autumn := uv.ClimatologicalOzone(32.1, 34.85, time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC))
+	if spring < 250 || spring > 400 || autumn < 250 || autumn > 400 {
+		t.Errorf("Expected ozone values within 250-400 DU but got %.1f and %.1f", spring, autumn)
+	}
+	if spring <= autumn {
+		t.Errorf("Expected more ozone in spring (%.1f) than in autumn (%.1f)", spring, autumn)
+	}
+
+	equator := uv.ClimatologicalOzone(0, 34.85, time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC))
+	if equator != 235 {
+		t.Errorf("Expected %.1f DU at the equator but got %.1f", 235.0, equator)
+	}
+}
+
+func TestClearSkyModel_Measure(t *testing.T) {
+	summerNoon := time.Date(2021, time.June, 21, 9, 40, 0, 0, time.UTC)
+	model := &uv.ClearSkyModel{TotalOzone: 300, Now: func() time.Time { return summerNoon }}
+	measurement, err := model.Measure(uv.TelAviv)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if measurement.UVIndex < 10.5 || measurement.UVIndex > 12.5 {
+		t.Errorf("Expected a UV index of about 11 but got %.2f", measurement.UVIndex)
+	}
+
+	mountain := &uv.Location{DisplayName: "test", Latitude: uv.TelAviv.Latitude, Longitude: uv.TelAviv.Longitude, Elevation: 1000}
+	atElevationMeasurement, _ := model.Measure(mountain)
+	if atElevationMeasurement.UVIndex <= measurement.UVIndex {
+		t.Errorf("Expected the elevation of the location to increase the UV index but got %.2f", atElevationMeasurement.UVIndex)
+	}
+}
+
+func TestClearSkyModel_MeasureInvalidCoordinates(t *testing.T) {
+	model := &uv.ClearSkyModel{}
+	_, err := model.Measure(&uv.Location{DisplayName: "test", Latitude: 222.222, Longitude: 34.85})
+	if err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestClearSkyModel_CheckPlausible(t *testing.T) {
+	winterNoon := time.Date(2021, time.December, 21, 9, 40, 0, 0, time.UTC)
+	model := &uv.ClearSkyModel{Now: func() time.Time { return winterNoon }}
+	if err := model.CheckPlausible(uv.TelAviv, 3); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := model.CheckPlausible(uv.TelAviv, 11); err == nil {
+		t.Error("Expected a UV index of 11 in December to be implausible")
+	}
+}
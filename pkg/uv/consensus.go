@@ -0,0 +1,249 @@
+package uv
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+)
+
+type ConsensusMethod int
+
+const (
+	ConsensusMedian ConsensusMethod = iota
+	ConsensusTrimmedMean
+	ConsensusWeightedMean
+)
+
+func (method ConsensusMethod) String() string {
+	switch method {
+	case ConsensusMedian:
+		return "median"
+	case ConsensusTrimmedMean:
+		return "trimmed mean"
+	case ConsensusWeightedMean:
+		return "weighted mean"
+	}
+	return fmt.Sprintf("ConsensusMethod(%d)", int(method))
+}
+
+// WeightedProvider is a single member of a ConsensusProvider. Weight is only
+// used by ConsensusWeightedMean and defaults to 1 when left unset. A weight of
+// zero excludes the provider from the consensus and its spread with any
+// method, and negative weights are invalid.
+type WeightedProvider struct {
+	Name     string
+	Provider MeasurementProvider
+	Weight   *float32
+}
+
+type ProviderReading struct {
+	Name        string
+	UVIndex     float32
+	Weight      *float32
+	Measurement *Measurement
+	Error       error
+}
+
+type Consensus struct {
+	UVIndex  float32
+	Spread   float32
+	Diverged bool
+	Readings []*ProviderReading
+}
+
+// ConsensusProvider queries all of its providers concurrently and combines the
+// successful readings into a single UV index. Providers that fail are skipped,
+// the measurement only fails if none of them returned a reading.
+type ConsensusProvider struct {
+	Providers           []*WeightedProvider
+	Method              ConsensusMethod
+	TrimFraction        float32
+	DivergenceThreshold float32
+	OnDivergence        func(location *Location, consensus *Consensus)
+
+	mutex             sync.Mutex
+	latestForLocation map[string]*Consensus
+}
+
+// Measure returns the consensus UV index with the spread between providers as
+// its uncertainty. The remaining values are taken from the first provider that
+// supplied them.
+func (consensusProvider *ConsensusProvider) Measure(locationToMeasure *Location) (*Measurement, error) {
+	consensus, consensusError := consensusProvider.MeasureConsensus(locationToMeasure)
+	if consensusError != nil {
+		return nil, consensusError
+	}
+	spread := consensus.Spread
+	measurement := &Measurement{Source: "consensus", UVIndex: consensus.UVIndex, Uncertainty: &spread}
+	for _, reading := range consensus.Readings {
+		if reading.Error != nil {
+			continue
+		}
+		if reading.Measurement.ObservedAt.After(measurement.ObservedAt) {
+			measurement.ObservedAt = reading.Measurement.ObservedAt
+		}
+		if measurement.ClearSkyUVIndex == nil {
+			measurement.ClearSkyUVIndex = reading.Measurement.ClearSkyUVIndex
+		}
+		if measurement.CloudCover == nil {
+			measurement.CloudCover = reading.Measurement.CloudCover
+		}
+		if measurement.Ozone == nil {
+			measurement.Ozone = reading.Measurement.Ozone
+		}
+		if measurement.Sunrise.IsZero() {
+			measurement.Sunrise = reading.Measurement.Sunrise
+		}
+		if measurement.Sunset.IsZero() {
+			measurement.Sunset = reading.Measurement.Sunset
+		}
+	}
+	return measurement, nil
+}
+
+func (consensusProvider *ConsensusProvider) MeasureConsensus(locationToMeasure *Location) (*Consensus, error) {
+	if len(consensusProvider.Providers) == 0 {
+		return nil, errors.New("no providers were configured for consensus")
+	}
+
+	readings := make([]*ProviderReading, len(consensusProvider.Providers))
+	var waitGroup sync.WaitGroup
+	for i, weightedProvider := range consensusProvider.Providers {
+		waitGroup.Add(1)
+		go func(i int, weightedProvider *WeightedProvider) {
+			defer waitGroup.Done()
+			measurement, measurementError := weightedProvider.Provider.Measure(locationToMeasure)
+			if measurementError == nil && measurement == nil {
+				measurementError = errors.New("provider returned no measurement")
+			}
+			reading := &ProviderReading{Name: weightedProvider.Name, Weight: weightedProvider.Weight, Measurement: measurement, Error: measurementError}
+			if measurementError == nil {
+				reading.UVIndex = measurement.UVIndex
+			}
+			readings[i] = reading
+		}(i, weightedProvider)
+	}
+	waitGroup.Wait()
+
+	successfulReadings := []*ProviderReading{}
+	for _, reading := range readings {
+		if reading.Error != nil {
+			log.Println(fmt.Errorf("provider %s failed to measure %s: %w", reading.Name, locationToMeasure.DisplayName, reading.Error))
+			continue
+		}
+		if reading.Weight != nil && *reading.Weight == 0 {
+			continue
+		}
+		successfulReadings = append(successfulReadings, reading)
+	}
+	if len(successfulReadings) == 0 {
+		return nil, fmt.Errorf("all %d providers failed to measure %s", len(readings), locationToMeasure.DisplayName)
+	}
+
+	uvIndex, combineError := CombineReadings(successfulReadings, consensusProvider.Method, consensusProvider.TrimFraction)
+	if combineError != nil {
+		return nil, fmt.Errorf("failed to combine readings for %s: %w", locationToMeasure.DisplayName, combineError)
+	}
+	spread := readingsSpread(successfulReadings)
+	consensus := &Consensus{
+		UVIndex:  uvIndex,
+		Spread:   spread,
+		Diverged: consensusProvider.DivergenceThreshold > 0 && spread > consensusProvider.DivergenceThreshold,
+		Readings: readings,
+	}
+
+	if consensus.Diverged {
+		log.Printf("Providers diverge by %.1f for %s\n", spread, locationToMeasure.DisplayName)
+		if consensusProvider.OnDivergence != nil {
+			consensusProvider.OnDivergence(locationToMeasure, consensus)
+		}
+	}
+
+	consensusProvider.mutex.Lock()
+	defer consensusProvider.mutex.Unlock()
+	if consensusProvider.latestForLocation == nil {
+		consensusProvider.latestForLocation = map[string]*Consensus{}
+	}
+	consensusProvider.latestForLocation[locationToMeasure.DisplayName] = consensus
+	return consensus, nil
+}
+
+// LatestConsensus returns the most recent consensus computed for the location,
+// or nil if it was never measured.
+func (consensusProvider *ConsensusProvider) LatestConsensus(location *Location) *Consensus {
+	consensusProvider.mutex.Lock()
+	defer consensusProvider.mutex.Unlock()
+	return consensusProvider.latestForLocation[location.DisplayName]
+}
+
+func CombineReadings(readings []*ProviderReading, method ConsensusMethod, trimFraction float32) (float32, error) {
+	if len(readings) == 0 {
+		return 0, errors.New("no readings to combine")
+	}
+	values := make([]float64, len(readings))
+	for i, reading := range readings {
+		values[i] = float64(reading.UVIndex)
+	}
+	sort.Float64s(values)
+
+	switch method {
+	case ConsensusMedian:
+		return float32(median(values)), nil
+	case ConsensusTrimmedMean:
+		if trimFraction < 0 || trimFraction >= 0.5 {
+			return 0, fmt.Errorf("trim fraction must be in [0, 0.5) but got %.2f", trimFraction)
+		}
+		trimCount := int(math.Floor(float64(len(values)) * float64(trimFraction)))
+		return float32(mean(values[trimCount : len(values)-trimCount])), nil
+	case ConsensusWeightedMean:
+		var weightedSum, totalWeight float64
+		for _, reading := range readings {
+			weight := 1.0
+			if reading.Weight != nil {
+				weight = float64(*reading.Weight)
+			}
+			if weight < 0 {
+				return 0, fmt.Errorf("provider %s has a negative weight %.2f", reading.Name, weight)
+			}
+			weightedSum += weight * float64(reading.UVIndex)
+			totalWeight += weight
+		}
+		if totalWeight == 0 {
+			return 0, errors.New("all readings have a weight of zero")
+		}
+		return float32(weightedSum / totalWeight), nil
+	}
+	return 0, fmt.Errorf("unknown consensus method %s", method)
+}
+
+func readingsSpread(readings []*ProviderReading) float32 {
+	lowest, highest := readings[0].UVIndex, readings[0].UVIndex
+	for _, reading := range readings[1:] {
+		if reading.UVIndex < lowest {
+			lowest = reading.UVIndex
+		}
+		if reading.UVIndex > highest {
+			highest = reading.UVIndex
+		}
+	}
+	return highest - lowest
+}
+
+func median(sortedValues []float64) float64 {
+	middle := len(sortedValues) / 2
+	if len(sortedValues)%2 == 0 {
+		return (sortedValues[middle-1] + sortedValues[middle]) / 2
+	}
+	return sortedValues[middle]
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,225 @@
+package uv_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func consensusProviders(measurements map[string]float32, failing ...string) []*uv.WeightedProvider {
+	providers := []*uv.WeightedProvider{}
+	for name, measurement := range measurements {
+		provider := &testMeasurementProvider{MeasurementForLocation: map[string]float32{"test": measurement}}
+		providers = append(providers, &uv.WeightedProvider{Name: name, Provider: provider})
+	}
+	for _, name := range failing {
+		provider := &testMeasurementProvider{FailOnLocation: map[string]bool{"test": true}}
+		providers = append(providers, &uv.WeightedProvider{Name: name, Provider: provider})
+	}
+	return providers
+}
+
+func TestConsensusProvider_Median(t *testing.T) {
+	location := &uv.Location{DisplayName: "test"}
+	consensusProvider := &uv.ConsensusProvider{
+		Providers: consensusProviders(map[string]float32{"a": 2, "b": 7, "c": 3}),
+		Method:    uv.ConsensusMedian,
+	}
+	measurement, err := consensusProvider.Measure(location)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if measurement.UVIndex != 3 {
+		t.Errorf("Expected median %.1f but got %.1f", 3.0, measurement.UVIndex)
+	}
+	if measurement.Uncertainty == nil || *measurement.Uncertainty != 5 {
+		t.Error("Expected the spread to be attached as the uncertainty")
+	}
+	if measurement.Source != "consensus" {
+		t.Errorf("Expected source %s but got %s", "consensus", measurement.Source)
+	}
+	consensus := consensusProvider.LatestConsensus(location)
+	if consensus.Spread != 5 {
+		t.Errorf("Expected spread %.1f but got %.1f", 5.0, consensus.Spread)
+	}
+	if consensus.Diverged {
+		t.Error("Providers should not be flagged as diverging without a threshold")
+	}
+}
+
+func TestConsensusProvider_Divergence(t *testing.T) {
+	location := &uv.Location{DisplayName: "test"}
+	var divergedLocation *uv.Location
+	consensusProvider := &uv.ConsensusProvider{
+		Providers:           consensusProviders(map[string]float32{"a": 4, "b": 6.5}),
+		Method:              uv.ConsensusMedian,
+		DivergenceThreshold: 2,
+		OnDivergence: func(location *uv.Location, consensus *uv.Consensus) {
+			divergedLocation = location
+		},
+	}
+	consensus, err := consensusProvider.MeasureConsensus(location)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !consensus.Diverged {
+		t.Error("Providers should have been flagged as diverging")
+	}
+	if divergedLocation != location {
+		t.Error("Expected the divergence callback to be called")
+	}
+	if consensus.UVIndex != 5.25 {
+		t.Errorf("Expected median %.2f but got %.2f", 5.25, consensus.UVIndex)
+	}
+}
+
+func TestConsensusProvider_SkipsFailingProviders(t *testing.T) {
+	location := &uv.Location{DisplayName: "test"}
+	consensusProvider := &uv.ConsensusProvider{
+		Providers: consensusProviders(map[string]float32{"a": 4}, "b"),
+		Method:    uv.ConsensusMedian,
+	}
+	consensus, err := consensusProvider.MeasureConsensus(location)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if consensus.UVIndex != 4 {
+		t.Errorf("Expected %.1f but got %.1f", 4.0, consensus.UVIndex)
+	}
+	if len(consensus.Readings) != 2 {
+		t.Errorf("Expected %d readings but got %d", 2, len(consensus.Readings))
+	}
+}
+
+func TestConsensusProvider_ZeroWeightDoesNotDiverge(t *testing.T) {
+	location := &uv.Location{DisplayName: "test"}
+	providers := consensusProviders(map[string]float32{"a": 4, "b": 4.5})
+	providers = append(providers, &uv.WeightedProvider{
+		Name:     "c",
+		Provider: &testMeasurementProvider{MeasurementForLocation: map[string]float32{"test": 11}},
+		Weight:   weight(0),
+	})
+	consensusProvider := &uv.ConsensusProvider{
+		Providers:           providers,
+		Method:              uv.ConsensusMedian,
+		DivergenceThreshold: 2,
+	}
+	consensus, err := consensusProvider.MeasureConsensus(location)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if consensus.Diverged {
+		t.Error("A provider with a zero weight should not make the providers diverge")
+	}
+	if consensus.Spread != 0.5 {
+		t.Errorf("Expected spread %.1f but got %.1f", 0.5, consensus.Spread)
+	}
+	if consensus.UVIndex != 4.25 {
+		t.Errorf("Expected median %.2f but got %.2f", 4.25, consensus.UVIndex)
+	}
+}
+
+type emptyMeasurementProvider struct{}
+
+func (provider *emptyMeasurementProvider) Measure(locationToMeasure *uv.Location) (*uv.Measurement, error) {
+	return nil, nil
+}
+
+func TestConsensusProvider_EmptyMeasurement(t *testing.T) {
+	providers := consensusProviders(map[string]float32{"a": 4})
+	providers = append(providers, &uv.WeightedProvider{Name: "b", Provider: &emptyMeasurementProvider{}})
+	consensusProvider := &uv.ConsensusProvider{Providers: providers, Method: uv.ConsensusMedian}
+	measurement, err := consensusProvider.Measure(&uv.Location{DisplayName: "test"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if measurement.UVIndex != 4 {
+		t.Errorf("Expected %.1f but got %.1f", 4.0, measurement.UVIndex)
+	}
+
+	consensusProvider = &uv.ConsensusProvider{Providers: []*uv.WeightedProvider{{Name: "b", Provider: &emptyMeasurementProvider{}}}}
+	_, err = consensusProvider.Measure(&uv.Location{DisplayName: "test"})
+	if err == nil {
+		t.Error("Expected an error when the only provider returns no measurement")
+	}
+}
+
+func TestConsensusProvider_AllProvidersFail(t *testing.T) {
+	consensusProvider := &uv.ConsensusProvider{Providers: consensusProviders(nil, "a", "b")}
+	_, err := consensusProvider.Measure(&uv.Location{DisplayName: "test"})
+	if err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestConsensusProvider_NoProviders(t *testing.T) {
+	consensusProvider := &uv.ConsensusProvider{}
+	_, err := consensusProvider.Measure(&uv.Location{DisplayName: "test"})
+	if err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func weight(value float32) *float32 {
+	return &value
+}
+
+func TestCombineReadings(t *testing.T) {
+	readings := []*uv.ProviderReading{
+		{Name: "a", UVIndex: 1, Weight: weight(1)},
+		{Name: "b", UVIndex: 5, Weight: weight(3)},
+		{Name: "c", UVIndex: 6},
+		{Name: "d", UVIndex: 20, Weight: weight(1)},
+	}
+
+	median, _ := uv.CombineReadings(readings, uv.ConsensusMedian, 0)
+	if median != 5.5 {
+		t.Errorf("Expected median %.2f but got %.2f", 5.5, median)
+	}
+
+	trimmedMean, _ := uv.CombineReadings(readings, uv.ConsensusTrimmedMean, 0.25)
+	if trimmedMean != 5.5 {
+		t.Errorf("Expected trimmed mean %.2f but got %.2f", 5.5, trimmedMean)
+	}
+
+	weightedMean, _ := uv.CombineReadings(readings, uv.ConsensusWeightedMean, 0)
+	if math.Abs(float64(weightedMean)-42.0/6.0) > 0.0001 {
+		t.Errorf("Expected weighted mean %.2f but got %.2f", 42.0/6.0, weightedMean)
+	}
+
+	_, err := uv.CombineReadings(readings, uv.ConsensusTrimmedMean, 0.5)
+	if err == nil {
+		t.Error("Expected an error for an invalid trim fraction")
+	}
+
+	_, err = uv.CombineReadings(nil, uv.ConsensusMedian, 0)
+	if err == nil {
+		t.Error("Expected an error for no readings")
+	}
+}
+
+func TestCombineReadings_Weights(t *testing.T) {
+	readings := []*uv.ProviderReading{
+		{Name: "a", UVIndex: 4, Weight: weight(1)},
+		{Name: "b", UVIndex: 10, Weight: weight(0)},
+		{Name: "c", UVIndex: 6},
+	}
+	weightedMean, err := uv.CombineReadings(readings, uv.ConsensusWeightedMean, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weightedMean != 5 {
+		t.Errorf("Expected a zero weight to exclude the provider from the weighted mean %.2f but got %.2f", 5.0, weightedMean)
+	}
+
+	_, err = uv.CombineReadings([]*uv.ProviderReading{{Name: "a", UVIndex: 4, Weight: weight(-1)}}, uv.ConsensusWeightedMean, 0)
+	if err == nil {
+		t.Error("Expected an error for a negative weight")
+	}
+
+	_, err = uv.CombineReadings([]*uv.ProviderReading{{Name: "a", UVIndex: 4, Weight: weight(0)}}, uv.ConsensusWeightedMean, 0)
+	if err == nil {
+		t.Error("Expected an error when every weight is zero")
+	}
+}
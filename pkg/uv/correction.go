@@ -0,0 +1,123 @@
+package uv
+
+import (
+	"fmt"
+	"math"
+)
+
+// Surface is the type of ground around a location.
+type Surface int
+
+const (
+	SurfaceUnknown Surface = iota
+	SurfaceGrass
+	SurfaceSand
+	SurfaceWater
+	SurfaceSnow
+)
+
+// surfaceAlbedos are the fractions of UV that each surface reflects, as
+// published in the WHO Global Solar UV Index practical guide: grass reflects
+// less than 10%, dry beach sand about 15%, water about 10% and fresh snow up to
+// 80%.
+var surfaceAlbedos = map[Surface]float64{
+	SurfaceUnknown: 0,
+	SurfaceGrass:   0.03,
+	SurfaceSand:    0.15,
+	SurfaceWater:   0.10,
+	SurfaceSnow:    0.80,
+}
+
+func (surface Surface) String() string {
+	switch surface {
+	case SurfaceUnknown:
+		return "unknown"
+	case SurfaceGrass:
+		return "grass"
+	case SurfaceSand:
+		return "sand"
+	case SurfaceWater:
+		return "water"
+	case SurfaceSnow:
+		return "snow"
+	}
+	return fmt.Sprintf("Surface(%d)", int(surface))
+}
+
+// Albedo is the fraction of UV that the surface reflects.
+func (surface Surface) Albedo() float64 {
+	return surfaceAlbedos[surface]
+}
+
+// elevationFactor is the WHO rule of thumb of 10% more UV per 1000 m.
+func elevationFactor(elevation float64) float64 {
+	return 1 + 0.1*math.Max(0, elevation)/1000
+}
+
+// CorrectionFactor scales a provider's UV index to the location. Provider
+// values are taken as sea level values over unreflective ground, so the factor
+// adds 10% per 1000 m of elevation and the UV that the surface reflects back
+// on top of the direct UV.
+func CorrectionFactor(location *Location) float32 {
+	return float32(elevationFactor(location.Elevation) * (1 + location.Surface.Albedo()))
+}
+
+// CorrectedProvider applies the CorrectionFactor of the location to the
+// measurements of a gridded provider, and keeps the provider's value in
+// RawUVIndex. Local weather stations already measure the location and should
+// not be wrapped.
+type CorrectedProvider struct {
+	Provider MeasurementProvider
+}
+
+func (correctedProvider *CorrectedProvider) Measure(locationToMeasure *Location) (*Measurement, error) {
+	measurement, measurementError := correctedProvider.Provider.Measure(locationToMeasure)
+	if measurementError != nil {
+		return nil, measurementError
+	}
+	factor := CorrectionFactor(locationToMeasure)
+	if factor == 1 {
+		return measurement, nil
+	}
+	rawUVIndex := measurement.UVIndex
+	measurement.RawUVIndex = &rawUVIndex
+	measurement.UVIndex = rawUVIndex * factor
+	if measurement.ClearSkyUVIndex != nil {
+		clearSkyUVIndex := *measurement.ClearSkyUVIndex * factor
+		measurement.ClearSkyUVIndex = &clearSkyUVIndex
+	}
+	return measurement, nil
+}
+
+// CorrectedForecastProvider applies the CorrectionFactor of the location to
+// the forecasts of a gridded provider.
+type CorrectedForecastProvider struct {
+	Provider ForecastProvider
+}
+
+func (correctedProvider *CorrectedForecastProvider) Forecast(locationToForecast *Location) (*Forecast, error) {
+	forecast, forecastError := correctedProvider.Provider.Forecast(locationToForecast)
+	if forecastError != nil {
+		return nil, forecastError
+	}
+	factor := CorrectionFactor(locationToForecast)
+	if factor == 1 {
+		return forecast, nil
+	}
+	// The forecast may be shared by a cache, so the corrected values go into a
+	// copy
+	corrected := &Forecast{FetchedAt: forecast.FetchedAt, Source: forecast.Source}
+	for _, hour := range forecast.Hourly {
+		correctedHour := *hour
+		rawUVIndex := hour.UVIndex
+		correctedHour.RawUVIndex = &rawUVIndex
+		correctedHour.UVIndex *= factor
+		corrected.Hourly = append(corrected.Hourly, &correctedHour)
+	}
+	for _, day := range forecast.Daily {
+		correctedDay := *day
+		correctedDay.MaxUVIndex *= factor
+		corrected.Daily = append(corrected.Daily, &correctedDay)
+	}
+	return corrected, nil
+}
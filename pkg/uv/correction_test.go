@@ -0,0 +1,145 @@
+package uv_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func TestCorrectionFactor(t *testing.T) {
+	tests := []struct {
+		location *uv.Location
+		expected float32
+	}{
+		{&uv.Location{}, 1},
+		{&uv.Location{Elevation: -400}, 1},
+		{&uv.Location{Elevation: 2000}, 1.2},
+		{&uv.Location{Surface: uv.SurfaceSand}, 1.15},
+		{&uv.Location{Elevation: 3000, Surface: uv.SurfaceSnow}, 2.34},
+	}
+	for _, test := range tests {
+		if factor := uv.CorrectionFactor(test.location); math.Abs(float64(factor-test.expected)) > 0.0001 {
+			t.Errorf("Expected a factor of %.2f at %.0f m over %s but got %.4f", test.expected, test.location.Elevation, test.location.Surface, factor)
+		}
+	}
+}
+
+func TestCorrectedProvider(t *testing.T) {
+	beach := &uv.Location{DisplayName: "beach", Surface: uv.SurfaceSand}
+	provider := &testMeasurementProvider{MeasurementForLocation: map[string]float32{beach.DisplayName: 6, uv.TelAviv.DisplayName: 6}}
+	correctedProvider := &uv.CorrectedProvider{Provider: provider}
+
+	measurement, err := correctedProvider.Measure(beach)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if math.Abs(float64(measurement.UVIndex-6.9)) > 0.0001 || measurement.RawUVIndex == nil || *measurement.RawUVIndex != 6 {
+		t.Errorf("Unexpected corrected measurement %+v", measurement)
+	}
+	if uv.CategoryOf(measurement.UVIndex) != uv.CategoryHigh {
+		t.Errorf("Expected the corrected value to drive the category but got %s", uv.CategoryOf(measurement.UVIndex))
+	}
+
+	measurement, _ = correctedProvider.Measure(&uv.Location{DisplayName: uv.TelAviv.DisplayName})
+	if measurement.UVIndex != 6 || measurement.RawUVIndex != nil {
+		t.Errorf("Expected no correction without elevation or surface but got %+v", measurement)
+	}
+
+	provider.FailOnLocation = map[string]bool{beach.DisplayName: true}
+	if _, err := correctedProvider.Measure(beach); err == nil {
+		t.Error("Expected the provider's error")
+	}
+}
+
+func TestCorrectedForecastProvider(t *testing.T) {
+	mountain := &uv.Location{DisplayName: "mountain", Elevation: 1000}
+	forecast := hourlyForecast(time.Date(2021, time.June, 21, 9, 0, 0, 0, time.UTC), 5, 10)
+	forecast.Daily = []*uv.DailyForecast{{MaxUVIndex: 10}}
+	correctedProvider := &uv.CorrectedForecastProvider{Provider: &testForecastProvider{ForecastForLocation: map[string]*uv.Forecast{mountain.DisplayName: forecast}}}
+
+	corrected, err := correctedProvider.Forecast(mountain)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if corrected.Hourly[1].UVIndex != 11 || corrected.Daily[0].MaxUVIndex != 11 {
+		t.Errorf("Unexpected corrected forecast %+v %+v", corrected.Hourly[1], corrected.Daily[0])
+	}
+	if corrected.Hourly[1].RawUVIndex == nil || *corrected.Hourly[1].RawUVIndex != 10 {
+		t.Errorf("Expected the raw value to be kept but got %v", corrected.Hourly[1].RawUVIndex)
+	}
+	if forecast.Hourly[1].UVIndex != 10 {
+		t.Error("Expected the original forecast to be left untouched")
+	}
+}
+
+func TestTelAvivAlerts_RawUVIndex(t *testing.T) {
+	rawUVIndex := float32(6.8)
+	alert := uv.TelAvivAlerts{}.Moderate(&uv.Measurement{UVIndex: 7.8, RawUVIndex: &rawUVIndex})
+	expected := "The UV Index in Tel-Aviv is 7.8 (6.8 before elevation and surface correction). Seek shade"
+	if !strings.HasPrefix(alert, expected) {
+		t.Errorf("Expected %s to start with %s", alert, expected)
+	}
+}
+
+func TestHistory_RecordsRawUVIndex(t *testing.T) {
+	history := openTestHistory(t)
+	observedAt := time.Date(2021, time.June, 21, 9, 0, 0, 0, time.UTC)
+	rawUVIndex := float32(6)
+	if err := history.Record(uv.TelAviv, &uv.Measurement{ObservedAt: observedAt, UVIndex: 6.9, RawUVIndex: &rawUVIndex}); err != nil {
+		t.Fatal(err)
+	}
+	records, _ := history.Range(uv.TelAviv, observedAt, observedAt.Add(time.Minute))
+	if len(records) != 1 || records[0].RawUVIndex == nil || *records[0].RawUVIndex != 6 || records[0].Category != uv.CategoryHigh {
+		t.Errorf("Unexpected records %+v", records)
+	}
+}
+
+func TestTemplates_RawUVIndex(t *testing.T) {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	rawUVIndex := float32(0.5)
+	guidance := uv.NewVitaminDGuidance(uv.TelAviv, &uv.Measurement{UVIndex: 0.6, RawUVIndex: &rawUVIndex})
+	alert, err := uv.RenderTemplate("vitamin D alert", uv.TelAvivVitaminDAlert, guidance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "The UV index in Tel-Aviv is 0.6 (0.5 before elevation and surface correction). It's safe"
+	if !strings.HasPrefix(alert, expected) {
+		t.Errorf("Expected %s to start with %s", alert, expected)
+	}
+
+	mountain := &uv.Location{DisplayName: "mountain", IANA: "Asia/Jerusalem", Latitude: 32.1, Longitude: 34.85, Elevation: 1000}
+	forecast := hourlyForecast(time.Date(2021, time.June, 21, 10, 0, 0, 0, jerusalem), 5, 10, 5)
+	corrected, _ := (&uv.CorrectedForecastProvider{Provider: &testForecastProvider{ForecastForLocation: map[string]*uv.Forecast{mountain.DisplayName: forecast}}}).Forecast(mountain)
+	briefing, err := uv.NewMorningBriefing(mountain, corrected, time.Date(2021, time.June, 21, 7, 0, 0, 0, jerusalem))
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := uv.RenderTemplate("morning briefing", uv.TelAvivTemplates.MorningBriefing, briefing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "UV will peak at 11.0 (10.0 before elevation and surface correction) around 11:00."
+	if !strings.Contains(message, expected) {
+		t.Errorf("Expected %s to contain %s", message, expected)
+	}
+
+	rawPeak := float32(6)
+	summary, _ := uv.NewDailySummary(mountain, time.Date(2021, time.June, 21, 0, 0, 0, 0, jerusalem), []*uv.Measurement{{ObservedAt: time.Date(2021, time.June, 21, 12, 0, 0, 0, jerusalem), UVIndex: 6.6, RawUVIndex: &rawPeak}})
+	message, err = uv.RenderTemplate("evening summary", uv.TelAvivTemplates.EveningSummary, summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "UV peaked at 6.6 (6.0 before elevation and surface correction) at 12:00."
+	if !strings.Contains(message, expected) {
+		t.Errorf("Expected %s to contain %s", message, expected)
+	}
+
+	uncorrected := float32(6.61)
+	note := uv.TelAvivAlerts{}.Moderate(&uv.Measurement{UVIndex: 6.6, RawUVIndex: &uncorrected})
+	if strings.Contains(note, "before elevation") {
+		t.Errorf("Expected no raw value when it reads the same as the corrected one but got %s", note)
+	}
+}
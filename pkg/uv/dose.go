@@ -0,0 +1,29 @@
+package uv
+
+import "time"
+
+// A UV index of 1 is an erythemally weighted irradiance of 25 mW/m², and one
+// standard erythemal dose (SED) is 100 J/m².
+const (
+	ErythemalIrradiancePerUVIndex = 0.025
+	JoulesPerSED                  = 100.0
+)
+
+// DoseSED integrates the UV index over time into an erythemal dose in SED,
+// interpolating linearly between consecutive measurements. The measurements
+// must be sorted by observation time.
+func DoseSED(measurements []*Measurement) float64 {
+	dose := 0.0
+	for i := 1; i < len(measurements); i++ {
+		dose += intervalDoseSED(measurements[i-1].UVIndex, measurements[i].UVIndex, measurements[i].ObservedAt.Sub(measurements[i-1].ObservedAt))
+	}
+	return dose
+}
+
+func intervalDoseSED(fromUVIndex float32, toUVIndex float32, duration time.Duration) float64 {
+	if duration <= 0 {
+		return 0
+	}
+	averageUVIndex := float64(fromUVIndex+toUVIndex) / 2
+	return averageUVIndex * ErythemalIrradiancePerUVIndex * duration.Seconds() / JoulesPerSED
+}
@@ -0,0 +1,27 @@
+package uv_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func TestDoseSED(t *testing.T) {
+	start := time.Date(2021, time.June, 21, 9, 0, 0, 0, time.UTC)
+	measurements := []*uv.Measurement{
+		{ObservedAt: start, UVIndex: 4},
+		{ObservedAt: start.Add(time.Hour), UVIndex: 8},
+		{ObservedAt: start.Add(2 * time.Hour), UVIndex: 8},
+	}
+	// An hour at an average UV index of 6 and an hour at 8
+	expected := (6 + 8) * 0.025 * 3600 / 100
+	if dose := uv.DoseSED(measurements); math.Abs(dose-expected) > 0.0001 {
+		t.Errorf("Expected a dose of %.2f SED but got %.2f", expected, dose)
+	}
+
+	if dose := uv.DoseSED(measurements[:1]); dose != 0 {
+		t.Errorf("Expected no dose for a single measurement but got %.2f", dose)
+	}
+}
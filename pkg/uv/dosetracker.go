@@ -0,0 +1,160 @@
+package uv
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DailyDose is the erythemal dose that full sun exposure since local midnight
+// adds up to.
+type DailyDose struct {
+	Location  *Location
+	Date      time.Time
+	SED       float64
+	UpdatedAt time.Time
+}
+
+func (dose *DailyDose) JoulesPerSquareMeter() float64 {
+	return dose.SED * JoulesPerSED
+}
+
+// DoseTracker is a MeasurementRecorder that integrates the UV index of every
+// location into a cumulative daily dose, interpolating linearly between
+// measurements. The dose resets at midnight in the location's time zone, and
+// the interval that spans midnight is split between the two days.
+type DoseTracker struct {
+	mutex           sync.Mutex
+	doseForLocation map[string]*DailyDose
+	lastForLocation map[string]*Measurement
+}
+
+func NewDoseTracker() *DoseTracker {
+	return &DoseTracker{doseForLocation: map[string]*DailyDose{}, lastForLocation: map[string]*Measurement{}}
+}
+
+// Record adds the measurement to the dose. Measurements older than the latest
+// recorded one are ignored.
+func (tracker *DoseTracker) Record(location *Location, measurement *Measurement) error {
+	timeZone, timeZoneError := GetLocation(location.IANA)
+	if timeZoneError != nil {
+		return timeZoneError
+	}
+	localObservedAt := measurement.ObservedAt.In(timeZone)
+	midnight := time.Date(localObservedAt.Year(), localObservedAt.Month(), localObservedAt.Day(), 0, 0, 0, 0, timeZone)
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	last := tracker.lastForLocation[location.DisplayName]
+	if last != nil && !measurement.ObservedAt.After(last.ObservedAt) {
+		return nil
+	}
+	tracker.lastForLocation[location.DisplayName] = measurement
+
+	dose := tracker.doseForLocation[location.DisplayName]
+	if dose == nil || !dose.Date.Equal(midnight) {
+		dose = &DailyDose{Location: location, Date: midnight}
+		tracker.doseForLocation[location.DisplayName] = dose
+	}
+	dose.UpdatedAt = measurement.ObservedAt
+	if last == nil {
+		return nil
+	}
+	from, fromUVIndex := last.ObservedAt, last.UVIndex
+	if from.Before(midnight) {
+		fraction := float32(midnight.Sub(from)) / float32(measurement.ObservedAt.Sub(from))
+		from, fromUVIndex = midnight, last.UVIndex+fraction*(measurement.UVIndex-last.UVIndex)
+	}
+	dose.SED += intervalDoseSED(fromUVIndex, measurement.UVIndex, measurement.ObservedAt.Sub(from))
+	return nil
+}
+
+// Dose returns the dose of the location on the local date of now, which is
+// zero when nothing was recorded on it yet.
+func (tracker *DoseTracker) Dose(location *Location, now time.Time) (*DailyDose, error) {
+	timeZone, timeZoneError := GetLocation(location.IANA)
+	if timeZoneError != nil {
+		return nil, timeZoneError
+	}
+	localNow := now.In(timeZone)
+	midnight := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, timeZone)
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	dose := tracker.doseForLocation[location.DisplayName]
+	if dose == nil || !dose.Date.Equal(midnight) {
+		return &DailyDose{Location: location, Date: midnight}, nil
+	}
+	doseCopy := *dose
+	return &doseCopy, nil
+}
+
+// DoseAlert is posted when the daily dose passes a threshold.
+type DoseAlert struct {
+	Location  *Location
+	Threshold float64
+	Dose      *DailyDose
+}
+
+var DefaultDoseThresholds = []float64{2, 4, 8}
+
+// DoseThresholdTask posts when the daily dose of a location passes one of the
+// thresholds in SED. Only the highest threshold passed since the last post is
+// posted, so a bot started late in the day posts once.
+type DoseThresholdTask struct {
+	Locations  []*Location
+	Tracker    *DoseTracker
+	Reporters  []MessageReporter
+	Thresholds []float64
+
+	postedForLocation map[string]*DoseAlert
+}
+
+func (task *DoseThresholdTask) Run(now time.Time) error {
+	for _, location := range task.Locations {
+		if alertError := task.alert(location, now); alertError != nil {
+			log.Println(fmt.Errorf("failed to post the dose alert of %s: %w", location.DisplayName, alertError))
+		}
+	}
+	return nil
+}
+
+func (task *DoseThresholdTask) alert(location *Location, now time.Time) error {
+	dose, doseError := task.Tracker.Dose(location, now)
+	if doseError != nil {
+		return doseError
+	}
+	thresholds := append([]float64{}, task.Thresholds...)
+	if len(thresholds) == 0 {
+		thresholds = append(thresholds, DefaultDoseThresholds...)
+	}
+	sort.Float64s(thresholds)
+
+	var passed float64
+	for _, threshold := range thresholds {
+		if dose.SED >= threshold {
+			passed = threshold
+		}
+	}
+	posted := task.postedForLocation[location.DisplayName]
+	if passed == 0 || (posted != nil && posted.Dose.Date.Equal(dose.Date) && posted.Threshold >= passed) {
+		return nil
+	}
+
+	alert := &DoseAlert{Location: location, Threshold: passed, Dose: dose}
+	templates, templatesError := getTemplates(location)
+	if templatesError != nil {
+		return templatesError
+	}
+	message, renderError := RenderTemplate("dose threshold", templates.DoseThreshold, alert)
+	if renderError != nil {
+		return renderError
+	}
+	if task.postedForLocation == nil {
+		task.postedForLocation = map[string]*DoseAlert{}
+	}
+	task.postedForLocation[location.DisplayName] = alert
+	return reportMessage(task.Reporters, location, message)
+}
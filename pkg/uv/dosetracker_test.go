@@ -0,0 +1,102 @@
+package uv_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func recordDoses(t *testing.T, tracker *uv.DoseTracker, start time.Time, interval time.Duration, uvIndices ...float32) {
+	for i, uvIndex := range uvIndices {
+		if err := tracker.Record(uv.TelAviv, &uv.Measurement{ObservedAt: start.Add(time.Duration(i) * interval), UVIndex: uvIndex}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDoseTracker(t *testing.T) {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	tracker := uv.NewDoseTracker()
+	start := time.Date(2021, time.June, 21, 10, 0, 0, 0, jerusalem)
+	recordDoses(t, tracker, start, time.Hour, 8, 10, 10)
+
+	// 9 UVI for an hour and 10 UVI for another, at 0.025 W/m² per UVI
+	dose, err := tracker.Dose(uv.TelAviv, start.Add(3*time.Hour))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if math.Abs(dose.SED-17.1) > 0.001 || math.Abs(dose.JoulesPerSquareMeter()-1710) > 0.1 {
+		t.Errorf("Expected a dose of %.1f SED but got %f", 17.1, dose.SED)
+	}
+	if !dose.UpdatedAt.Equal(start.Add(2 * time.Hour)) {
+		t.Errorf("Unexpected update time %v", dose.UpdatedAt)
+	}
+
+	if err := tracker.Record(uv.TelAviv, &uv.Measurement{ObservedAt: start.Add(time.Hour), UVIndex: 11}); err != nil {
+		t.Fatal(err)
+	}
+	if dose, _ = tracker.Dose(uv.TelAviv, start.Add(3*time.Hour)); math.Abs(dose.SED-17.1) > 0.001 {
+		t.Errorf("Expected an out of order measurement to be ignored but got %f", dose.SED)
+	}
+}
+
+func TestDoseTracker_ResetsAtLocalMidnight(t *testing.T) {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	tracker := uv.NewDoseTracker()
+	recordDoses(t, tracker, time.Date(2021, time.June, 21, 23, 30, 0, 0, jerusalem), time.Hour, 2, 4, 4)
+
+	// UV is interpolated to 3 at midnight, so today starts with half an hour
+	// ramping from 3 to 4 and continues with an hour of 4, at 0.9 SED per UVI hour
+	today, _ := tracker.Dose(uv.TelAviv, time.Date(2021, time.June, 22, 2, 0, 0, 0, jerusalem))
+	if math.Abs(today.SED-5.175) > 0.001 {
+		t.Errorf("Expected a dose of %.3f SED but got %f", 5.175, today.SED)
+	}
+	if today.Date.Format("2006-01-02") != "2021-06-22" {
+		t.Errorf("Unexpected date %v", today.Date)
+	}
+
+	tomorrow, _ := tracker.Dose(uv.TelAviv, time.Date(2021, time.June, 23, 0, 30, 0, 0, jerusalem))
+	if tomorrow.SED != 0 {
+		t.Errorf("Expected no dose on a day without measurements but got %f", tomorrow.SED)
+	}
+}
+
+func TestDoseThresholdTask(t *testing.T) {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	tracker := uv.NewDoseTracker()
+	reporter := &testMessageReporter{}
+	task := &uv.DoseThresholdTask{Locations: []*uv.Location{uv.TelAviv}, Tracker: tracker, Reporters: []uv.MessageReporter{reporter}, Thresholds: []float64{4, 2}}
+	start := time.Date(2021, time.June, 21, 8, 0, 0, 0, jerusalem)
+
+	recordDoses(t, tracker, start, 30*time.Minute, 4, 4)
+	task.Run(start.Add(30 * time.Minute))
+	if len(reporter.Messages) != 0 {
+		t.Error("Expected no alert below the thresholds")
+	}
+
+	recordDoses(t, tracker, start.Add(time.Hour), 30*time.Minute, 4)
+	task.Run(start.Add(time.Hour))
+	task.Run(start.Add(65 * time.Minute))
+	if len(reporter.Messages) != 1 {
+		t.Fatalf("Expected a single alert but got %d", len(reporter.Messages))
+	}
+	expected := "You'd already have received 3.6 SED (360 J/m²) of UV in full sun in Tel-Aviv today ☀️ Unprotected fair skin burns from 2.5 SED."
+	if !strings.HasPrefix(reporter.Messages[0], expected) {
+		t.Errorf("Expected %s to start with %s", reporter.Messages[0], expected)
+	}
+
+	recordDoses(t, tracker, start.Add(90*time.Minute), time.Hour, 6)
+	task.Run(start.Add(90 * time.Minute))
+	if len(reporter.Messages) != 2 || !strings.Contains(reporter.Messages[1], "received 5.8 SED") {
+		t.Errorf("Expected an alert about the next threshold but got %v", reporter.Messages)
+	}
+
+	recordDoses(t, tracker, start.AddDate(0, 0, 1), time.Hour, 9, 9)
+	task.Run(start.AddDate(0, 0, 1).Add(time.Hour))
+	if len(reporter.Messages) != 3 {
+		t.Errorf("Expected the thresholds to reset on the next day but got %d alerts", len(reporter.Messages))
+	}
+}
@@ -0,0 +1,91 @@
+package uv
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const ecowittDateLayout = "2006-01-02 15:04:05"
+
+// EcowittReceiver accepts pushes made by Ecowitt weather stations using the
+// "customized upload" protocol in Ecowitt format, and exposes the latest UV
+// reading of each station as a MeasurementProvider. Stations are identified
+// by their PASSKEY.
+type EcowittReceiver struct {
+	LocationsByPassKey map[string]*Location
+	MaxAge             time.Duration
+	Now                func() time.Time
+
+	readings latestReadings
+}
+
+func NewEcowittReceiver(locationsByPassKey map[string]*Location, maxAge time.Duration) *EcowittReceiver {
+	return &EcowittReceiver{LocationsByPassKey: locationsByPassKey, MaxAge: maxAge}
+}
+
+func (receiver *EcowittReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 64*1024)
+	if parseError := r.ParseForm(); parseError != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	passKey := r.PostForm.Get("PASSKEY")
+	location, found := receiver.LocationsByPassKey[passKey]
+	if !found {
+		log.Printf("Rejected Ecowitt upload from unknown station %s\n", passKey)
+		http.Error(w, "unknown station", http.StatusForbidden)
+		return
+	}
+
+	uvIndex, parseError := strconv.ParseFloat(r.PostForm.Get("uv"), 32)
+	if parseError != nil {
+		log.Println(fmt.Errorf("failed to parse UV index of Ecowitt upload for %s: %w", location.DisplayName, parseError))
+		http.Error(w, "invalid uv", http.StatusBadRequest)
+		return
+	}
+	if uvIndex < 0 {
+		log.Printf("Rejected negative UV index %.1f of Ecowitt upload for %s\n", uvIndex, location.DisplayName)
+		http.Error(w, "invalid uv", http.StatusBadRequest)
+		return
+	}
+
+	receivedAt := receiver.now()
+	observedAt, dateError := time.Parse(ecowittDateLayout, r.PostForm.Get("dateutc"))
+	if dateError != nil {
+		observedAt = receivedAt
+	}
+	receiver.readings.update(location.DisplayName, &StationReading{
+		Station:    passKey,
+		UVIndex:    float32(uvIndex),
+		ObservedAt: observedAt,
+		ReceivedAt: receivedAt,
+	})
+	w.WriteHeader(http.StatusOK)
+}
+
+func (receiver *EcowittReceiver) Measure(locationToMeasure *Location) (*Measurement, error) {
+	reading, readingError := receiver.LatestReading(locationToMeasure)
+	if readingError != nil {
+		return nil, readingError
+	}
+	return reading.measurement("ecowitt:" + reading.Station), nil
+}
+
+func (receiver *EcowittReceiver) LatestReading(location *Location) (*StationReading, error) {
+	return receiver.readings.get(location, receiver.MaxAge, receiver.now())
+}
+
+func (receiver *EcowittReceiver) now() time.Time {
+	if receiver.Now != nil {
+		return receiver.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,88 @@
+package uv_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func postEcowittUpload(receiver *uv.EcowittReceiver, form url.Values) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/ecowitt", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	receiver.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestEcowittReceiver(t *testing.T) {
+	now := time.Date(2021, time.June, 21, 9, 0, 0, 0, time.UTC)
+	receiver := uv.NewEcowittReceiver(map[string]*uv.Location{"ABCD": uv.TelAviv}, 10*time.Minute)
+	receiver.Now = func() time.Time { return now }
+
+	_, err := receiver.Measure(uv.TelAviv)
+	if !errors.Is(err, uv.ErrNoReading) {
+		t.Errorf("Expected %v but got %v", uv.ErrNoReading, err)
+	}
+
+	form := url.Values{"PASSKEY": {"ABCD"}, "stationtype": {"EasyWeatherV1.5.9"}, "dateutc": {"2021-06-21 08:59:30"}, "uv": {"7"}}
+	response := postEcowittUpload(receiver, form)
+	if response.Code != http.StatusOK {
+		t.Errorf("Expected status %d but got %d", http.StatusOK, response.Code)
+	}
+
+	measurement, err := receiver.Measure(uv.TelAviv)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if measurement.UVIndex != 7 {
+		t.Errorf("Expected UV index %.1f but got %.1f", 7.0, measurement.UVIndex)
+	}
+	reading, _ := receiver.LatestReading(uv.TelAviv)
+	if !reading.ObservedAt.Equal(time.Date(2021, time.June, 21, 8, 59, 30, 0, time.UTC)) {
+		t.Errorf("Unexpected observation time %s", reading.ObservedAt)
+	}
+
+	now = now.Add(11 * time.Minute)
+	_, err = receiver.Measure(uv.TelAviv)
+	if !errors.Is(err, uv.ErrStaleReading) {
+		t.Errorf("Expected %v but got %v", uv.ErrStaleReading, err)
+	}
+}
+
+func TestEcowittReceiver_UnknownStation(t *testing.T) {
+	receiver := uv.NewEcowittReceiver(map[string]*uv.Location{"ABCD": uv.TelAviv}, 10*time.Minute)
+	response := postEcowittUpload(receiver, url.Values{"PASSKEY": {"EFGH"}, "uv": {"7"}})
+	if response.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d but got %d", http.StatusForbidden, response.Code)
+	}
+}
+
+func TestEcowittReceiver_InvalidUV(t *testing.T) {
+	receiver := uv.NewEcowittReceiver(map[string]*uv.Location{"ABCD": uv.TelAviv}, 10*time.Minute)
+	response := postEcowittUpload(receiver, url.Values{"PASSKEY": {"ABCD"}})
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d but got %d", http.StatusBadRequest, response.Code)
+	}
+	response = postEcowittUpload(receiver, url.Values{"PASSKEY": {"ABCD"}, "uv": {"-9999"}})
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d but got %d", http.StatusBadRequest, response.Code)
+	}
+	if _, err := receiver.Measure(uv.TelAviv); !errors.Is(err, uv.ErrNoReading) {
+		t.Errorf("Expected %v but got %v", uv.ErrNoReading, err)
+	}
+}
+
+func TestEcowittReceiver_OnlyAcceptsPost(t *testing.T) {
+	receiver := uv.NewEcowittReceiver(map[string]*uv.Location{"ABCD": uv.TelAviv}, 10*time.Minute)
+	recorder := httptest.NewRecorder()
+	receiver.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/ecowitt?PASSKEY=ABCD&uv=7", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d but got %d", http.StatusMethodNotAllowed, recorder.Code)
+	}
+}
@@ -0,0 +1,27 @@
+package uv
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FallbackProvider measures with each of its providers in order and returns
+// the first successful measurement.
+type FallbackProvider struct {
+	Providers []MeasurementProvider
+}
+
+func (fallbackProvider *FallbackProvider) Measure(locationToMeasure *Location) (*Measurement, error) {
+	if len(fallbackProvider.Providers) == 0 {
+		return nil, errors.New("no providers were configured for fallback")
+	}
+	var lastError error
+	for _, provider := range fallbackProvider.Providers {
+		measurement, measurementError := provider.Measure(locationToMeasure)
+		if measurementError == nil {
+			return measurement, nil
+		}
+		lastError = measurementError
+	}
+	return nil, fmt.Errorf("all providers failed to measure %s: %w", locationToMeasure.DisplayName, lastError)
+}
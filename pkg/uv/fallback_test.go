@@ -0,0 +1,32 @@
+package uv_test
+
+import (
+	"testing"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func TestFallbackProvider(t *testing.T) {
+	failing := &testMeasurementProvider{FailOnLocation: map[string]bool{"test": true}}
+	working := &testMeasurementProvider{MeasurementForLocation: map[string]float32{"test": 4.5}}
+	fallbackProvider := &uv.FallbackProvider{Providers: []uv.MeasurementProvider{failing, working}}
+
+	measurement, err := fallbackProvider.Measure(&uv.Location{DisplayName: "test"})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if measurement.UVIndex != 4.5 {
+		t.Errorf("Expected UV index %.1f but got %.1f", 4.5, measurement.UVIndex)
+	}
+}
+
+func TestFallbackProvider_AllFail(t *testing.T) {
+	failing := &testMeasurementProvider{FailOnLocation: map[string]bool{"test": true}}
+	fallbackProvider := &uv.FallbackProvider{Providers: []uv.MeasurementProvider{failing}}
+	if _, err := fallbackProvider.Measure(&uv.Location{DisplayName: "test"}); err == nil {
+		t.Error("Expected an error")
+	}
+	if _, err := (&uv.FallbackProvider{}).Measure(&uv.Location{DisplayName: "test"}); err == nil {
+		t.Error("Expected an error")
+	}
+}
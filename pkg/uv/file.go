@@ -0,0 +1,242 @@
+package uv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type FileFormat int
+
+const (
+	FileFormatCSV FileFormat = iota
+	FileFormatJSONL
+)
+
+// UnixTimestampLayout can be used as ColumnMapping.TimestampLayout when
+// timestamps are written as seconds since the Unix epoch.
+const UnixTimestampLayout = "unix"
+
+// ColumnMapping names the CSV header columns or JSONL keys holding each part of
+// a reading. Location values must match a Location.DisplayName.
+type ColumnMapping struct {
+	Timestamp       string
+	Location        string
+	UV              string
+	TimestampLayout string
+}
+
+var DefaultColumnMapping = ColumnMapping{Timestamp: "timestamp", Location: "location", UV: "uv", TimestampLayout: time.RFC3339}
+
+// FileProvider tails a CSV or JSONL file written by another process, such as an
+// instrument logger, and returns the most recent reading for each location.
+// CSV files must start with a header row. Lines that cannot be parsed are
+// logged and skipped.
+type FileProvider struct {
+	Path    string
+	Format  FileFormat
+	Columns ColumnMapping
+	MaxAge  time.Duration
+	Now     func() time.Time
+
+	mutex    sync.Mutex
+	offset   int64
+	partial  []byte
+	header   map[string]int
+	readings latestReadings
+}
+
+func NewFileProvider(path string, format FileFormat, maxAge time.Duration) *FileProvider {
+	return &FileProvider{Path: path, Format: format, Columns: DefaultColumnMapping, MaxAge: maxAge}
+}
+
+func (fileProvider *FileProvider) Measure(locationToMeasure *Location) (*Measurement, error) {
+	if tailError := fileProvider.tail(); tailError != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", fileProvider.Path, tailError)
+	}
+	reading, readingError := fileProvider.readings.get(locationToMeasure, 0, fileProvider.now())
+	if readingError != nil {
+		return nil, readingError
+	}
+	age := fileProvider.now().Sub(reading.ObservedAt)
+	if fileProvider.MaxAge > 0 && age > fileProvider.MaxAge {
+		return nil, fmt.Errorf("latest reading of %s in %s is %s old: %w", locationToMeasure.DisplayName, fileProvider.Path, age.Round(time.Second), ErrStaleReading)
+	}
+	return reading.measurement("file:" + fileProvider.Path), nil
+}
+
+// tail reads everything that was appended to the file since the last call. A
+// file that shrank is assumed to have been rotated and is read from the start.
+func (fileProvider *FileProvider) tail() error {
+	fileProvider.mutex.Lock()
+	defer fileProvider.mutex.Unlock()
+
+	file, openError := os.Open(fileProvider.Path)
+	if openError != nil {
+		return openError
+	}
+	defer file.Close()
+
+	info, statError := file.Stat()
+	if statError != nil {
+		return statError
+	}
+	if info.Size() < fileProvider.offset {
+		log.Printf("%s was truncated, reading it from the start\n", fileProvider.Path)
+		fileProvider.offset = 0
+		fileProvider.partial = nil
+		fileProvider.header = nil
+	}
+	if _, seekError := file.Seek(fileProvider.offset, io.SeekStart); seekError != nil {
+		return seekError
+	}
+	appended, readError := ioutil.ReadAll(file)
+	if readError != nil {
+		return readError
+	}
+	fileProvider.offset += int64(len(appended))
+
+	data := append(fileProvider.partial, appended...)
+	lastNewLine := bytes.LastIndexByte(data, '\n')
+	if lastNewLine < 0 {
+		fileProvider.partial = data
+		return nil
+	}
+	fileProvider.partial = append([]byte{}, data[lastNewLine+1:]...)
+
+	completeLines := data[:lastNewLine+1]
+	if fileProvider.Format == FileFormatJSONL {
+		fileProvider.parseJSONL(completeLines)
+		return nil
+	}
+	if parseError := fileProvider.parseCSV(completeLines); parseError != nil {
+		// Read the file from the start on the next call, so the error is
+		// reported until the header is fixed and no rows are lost meanwhile
+		fileProvider.offset = 0
+		fileProvider.partial = nil
+		fileProvider.header = nil
+		return parseError
+	}
+	return nil
+}
+
+func (fileProvider *FileProvider) parseCSV(lines []byte) error {
+	reader := csv.NewReader(bytes.NewReader(lines))
+	reader.FieldsPerRecord = -1
+	for {
+		record, recordError := reader.Read()
+		if recordError == io.EOF {
+			return nil
+		}
+		if recordError != nil {
+			log.Println(fmt.Errorf("skipping invalid CSV line in %s: %w", fileProvider.Path, recordError))
+			continue
+		}
+		if fileProvider.header == nil {
+			header := map[string]int{}
+			for i, column := range record {
+				header[column] = i
+			}
+			for _, column := range []string{fileProvider.Columns.Timestamp, fileProvider.Columns.Location, fileProvider.Columns.UV} {
+				if _, found := header[column]; !found {
+					return fmt.Errorf("CSV header is missing the %s column", column)
+				}
+			}
+			fileProvider.header = header
+			continue
+		}
+		values := map[string]interface{}{}
+		for column, i := range fileProvider.header {
+			if i < len(record) {
+				values[column] = record[i]
+			}
+		}
+		fileProvider.record(values)
+	}
+}
+
+func (fileProvider *FileProvider) parseJSONL(lines []byte) {
+	for _, line := range bytes.Split(lines, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		values := map[string]interface{}{}
+		if jsonError := json.Unmarshal(line, &values); jsonError != nil {
+			log.Println(fmt.Errorf("skipping invalid JSON line in %s: %w", fileProvider.Path, jsonError))
+			continue
+		}
+		fileProvider.record(values)
+	}
+}
+
+func (fileProvider *FileProvider) record(values map[string]interface{}) {
+	displayName, isString := values[fileProvider.Columns.Location].(string)
+	if !isString || displayName == "" {
+		log.Printf("skipping reading without a location in %s\n", fileProvider.Path)
+		return
+	}
+	uvIndex, uvError := parseFileNumber(values[fileProvider.Columns.UV])
+	if uvError != nil {
+		log.Println(fmt.Errorf("skipping reading with an invalid UV index for %s in %s: %w", displayName, fileProvider.Path, uvError))
+		return
+	}
+	observedAt, timestampError := fileProvider.parseTimestamp(values[fileProvider.Columns.Timestamp])
+	if timestampError != nil {
+		log.Println(fmt.Errorf("skipping reading with an invalid timestamp for %s in %s: %w", displayName, fileProvider.Path, timestampError))
+		return
+	}
+	fileProvider.readings.updateIfNewer(displayName, &StationReading{
+		Station:    fileProvider.Path,
+		UVIndex:    float32(uvIndex),
+		ObservedAt: observedAt,
+		ReceivedAt: fileProvider.now(),
+	})
+}
+
+func (fileProvider *FileProvider) parseTimestamp(value interface{}) (time.Time, error) {
+	layout := fileProvider.Columns.TimestampLayout
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	if layout == UnixTimestampLayout {
+		seconds, parseError := parseFileNumber(value)
+		if parseError != nil {
+			return time.Time{}, parseError
+		}
+		return time.Unix(0, int64(seconds*float64(time.Second))), nil
+	}
+	timestamp, isString := value.(string)
+	if !isString {
+		return time.Time{}, fmt.Errorf("expected a string but got %v", value)
+	}
+	return time.Parse(layout, timestamp)
+}
+
+func parseFileNumber(value interface{}) (float64, error) {
+	switch typedValue := value.(type) {
+	case float64:
+		return typedValue, nil
+	case string:
+		return strconv.ParseFloat(typedValue, 64)
+	case nil:
+		return 0, errors.New("value is missing")
+	}
+	return 0, fmt.Errorf("expected a number but got %v", value)
+}
+
+func (fileProvider *FileProvider) now() time.Time {
+	if fileProvider.Now != nil {
+		return fileProvider.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,125 @@
+package uv_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func appendToFile(t *testing.T, path string, content string) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileProvider_CSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "uv.csv")
+	now := time.Date(2021, time.June, 21, 9, 0, 0, 0, time.UTC)
+	fileProvider := uv.NewFileProvider(path, uv.FileFormatCSV, 30*time.Minute)
+	fileProvider.Now = func() time.Time { return now }
+
+	if _, err := fileProvider.Measure(uv.TelAviv); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+
+	appendToFile(t, path, "timestamp,location,uv\n2021-06-21T08:40:00Z,Tel-Aviv,5.5\n2021-06-21T08:50:00Z,Tel-Aviv,6.1\n2021-06-21T08:55:00Z,Hai")
+	measurement, err := fileProvider.Measure(uv.TelAviv)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if measurement.UVIndex != 6.1 {
+		t.Errorf("Expected UV index %.1f but got %.1f", 6.1, measurement.UVIndex)
+	}
+
+	appendToFile(t, path, "fa,2.0\n2021-06-21T08:30:00Z,Tel-Aviv,4.0\n2021-06-21T08:58:00Z,Tel-Aviv,not-a-number\n")
+	haifa := &uv.Location{DisplayName: "Haifa"}
+	measurement, err = fileProvider.Measure(haifa)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if measurement.UVIndex != 2 {
+		t.Errorf("Expected UV index %.1f but got %.1f", 2.0, measurement.UVIndex)
+	}
+	measurement, _ = fileProvider.Measure(uv.TelAviv)
+	if measurement.UVIndex != 6.1 {
+		t.Errorf("Expected an older reading not to replace the latest one but got %.1f", measurement.UVIndex)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := fileProvider.Measure(uv.TelAviv); !errors.Is(err, uv.ErrStaleReading) {
+		t.Errorf("Expected %v but got %v", uv.ErrStaleReading, err)
+	}
+}
+
+func TestFileProvider_CSVColumnMapping(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "uv.csv")
+	appendToFile(t, path, "station,epoch,index\nTel-Aviv,1624265400,7.25\n")
+	fileProvider := uv.NewFileProvider(path, uv.FileFormatCSV, 0)
+	fileProvider.Columns = uv.ColumnMapping{Timestamp: "epoch", Location: "station", UV: "index", TimestampLayout: uv.UnixTimestampLayout}
+
+	measurement, err := fileProvider.Measure(uv.TelAviv)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if measurement.UVIndex != 7.25 {
+		t.Errorf("Expected UV index %.2f but got %.2f", 7.25, measurement.UVIndex)
+	}
+}
+
+func TestFileProvider_CSVMissingColumn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "uv.csv")
+	appendToFile(t, path, "timestamp,location\n2021-06-21T08:50:00Z,Tel-Aviv\n")
+	fileProvider := uv.NewFileProvider(path, uv.FileFormatCSV, 0)
+	for i := 0; i < 2; i++ {
+		if _, err := fileProvider.Measure(uv.TelAviv); err == nil || err.Error() != "failed to read "+path+": CSV header is missing the uv column" {
+			t.Errorf("Expected the missing column to be reported on every call but got %v", err)
+		}
+	}
+}
+
+func TestFileProvider_JSONL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "uv.jsonl")
+	now := time.Date(2021, time.June, 21, 9, 0, 0, 0, time.UTC)
+	fileProvider := uv.NewFileProvider(path, uv.FileFormatJSONL, 30*time.Minute)
+	fileProvider.Now = func() time.Time { return now }
+
+	appendToFile(t, path, `{"timestamp": "2021-06-21T08:50:00Z", "location": "Tel-Aviv", "uv": 8.2}
+{{{
+{"timestamp": "2021-06-21T08:55:00Z", "location": "Tel-Aviv", "uv": "8.4"}
+`)
+	measurement, err := fileProvider.Measure(uv.TelAviv)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if measurement.UVIndex != 8.4 {
+		t.Errorf("Expected UV index %.1f but got %.1f", 8.4, measurement.UVIndex)
+	}
+}
+
+func TestFileProvider_Truncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "uv.jsonl")
+	fileProvider := uv.NewFileProvider(path, uv.FileFormatJSONL, 0)
+
+	appendToFile(t, path, `{"timestamp": "2021-06-21T08:50:00Z", "location": "Tel-Aviv", "uv": 8.2, "comment": "a long line"}`+"\n")
+	fileProvider.Measure(uv.TelAviv)
+
+	if err := os.WriteFile(path, []byte(`{"timestamp": "2021-06-21T09:50:00Z", "location": "Tel-Aviv", "uv": 9}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	measurement, err := fileProvider.Measure(uv.TelAviv)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if measurement.UVIndex != 9 {
+		t.Errorf("Expected UV index %.1f but got %.1f", 9.0, measurement.UVIndex)
+	}
+}
@@ -0,0 +1,139 @@
+package uv
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type HourlyForecast struct {
+	Time       time.Time
+	UVIndex    float32
+	CloudCover *float32
+	// RawUVIndex is the provider's value when UVIndex was corrected for the
+	// elevation and surface of the location
+	RawUVIndex *float32
+}
+
+type DailyForecast struct {
+	Date       time.Time
+	MaxUVIndex float32
+	Sunrise    time.Time
+	Sunset     time.Time
+}
+
+// Forecast holds the hourly UV index for the next 48 hours and the daily
+// maximum UV index for the next week.
+type Forecast struct {
+	FetchedAt time.Time
+	Source    string
+	Hourly    []*HourlyForecast
+	Daily     []*DailyForecast
+}
+
+type ForecastProvider interface {
+	Forecast(locationToForecast *Location) (*Forecast, error)
+}
+
+// HourlyBetween returns the hourly forecasts from start (inclusive) until end
+// (exclusive).
+func (forecast *Forecast) HourlyBetween(start time.Time, end time.Time) []*HourlyForecast {
+	hourly := []*HourlyForecast{}
+	for _, hour := range forecast.Hourly {
+		if !hour.Time.Before(start) && hour.Time.Before(end) {
+			hourly = append(hourly, hour)
+		}
+	}
+	return hourly
+}
+
+func (openweathermap *OpenWeatherMap) Forecast(locationToForecast *Location) (*Forecast, error) {
+	ocr := OneCallResponse{}
+	callError := openweathermap.oneCall("/data/2.5/onecall", locationToForecast, map[string]string{"exclude": "current,minutely,alerts"}, &ocr)
+	if callError != nil {
+		return nil, callError
+	}
+	if len(ocr.Hourly) == 0 && len(ocr.Daily) == 0 {
+		return nil, errors.New("response is missing the hourly and daily forecasts")
+	}
+
+	forecast := &Forecast{FetchedAt: time.Now(), Source: "openweathermap"}
+	for _, hour := range ocr.Hourly {
+		cloudCover := hour.Clouds
+		forecast.Hourly = append(forecast.Hourly, &HourlyForecast{Time: unixTime(hour.DT), UVIndex: hour.UVI, CloudCover: &cloudCover})
+	}
+	for _, day := range ocr.Daily {
+		forecast.Daily = append(forecast.Daily, &DailyForecast{
+			Date:       unixTime(day.DT),
+			MaxUVIndex: day.UVI,
+			Sunrise:    unixTime(day.Sunrise),
+			Sunset:     unixTime(day.Sunset),
+		})
+	}
+	return forecast, nil
+}
+
+// CachedForecastProvider keeps the forecast of each location for TTL so that
+// alerts and scheduled posts can share it without spending API quota.
+type CachedForecastProvider struct {
+	Provider ForecastProvider
+	TTL      time.Duration
+	Now      func() time.Time
+
+	mutex             sync.Mutex
+	cachedForLocation map[string]*Forecast
+	fetchForLocation  map[string]*sync.Mutex
+}
+
+func NewCachedForecastProvider(provider ForecastProvider, ttl time.Duration) *CachedForecastProvider {
+	return &CachedForecastProvider{Provider: provider, TTL: ttl}
+}
+
+// Forecast only holds the lock of the location while fetching, so a slow
+// location does not block the forecasts of the others.
+func (cachedProvider *CachedForecastProvider) Forecast(locationToForecast *Location) (*Forecast, error) {
+	fetchMutex := cachedProvider.fetchMutex(locationToForecast)
+	fetchMutex.Lock()
+	defer fetchMutex.Unlock()
+
+	now := time.Now()
+	if cachedProvider.Now != nil {
+		now = cachedProvider.Now()
+	}
+	cachedProvider.mutex.Lock()
+	cached, found := cachedProvider.cachedForLocation[locationToForecast.DisplayName]
+	cachedProvider.mutex.Unlock()
+	if found && now.Sub(cached.FetchedAt) < cachedProvider.TTL {
+		return cached, nil
+	}
+
+	forecast, forecastError := cachedProvider.Provider.Forecast(locationToForecast)
+	if forecastError != nil {
+		return nil, forecastError
+	}
+	// The wrapped provider may share the forecast, so the fetch time goes into
+	// a copy
+	fetched := *forecast
+	fetched.FetchedAt = now
+	cachedProvider.mutex.Lock()
+	defer cachedProvider.mutex.Unlock()
+	if cachedProvider.cachedForLocation == nil {
+		cachedProvider.cachedForLocation = map[string]*Forecast{}
+	}
+	cachedProvider.cachedForLocation[locationToForecast.DisplayName] = &fetched
+	return &fetched, nil
+}
+
+func (cachedProvider *CachedForecastProvider) fetchMutex(location *Location) *sync.Mutex {
+	cachedProvider.mutex.Lock()
+	defer cachedProvider.mutex.Unlock()
+	if cachedProvider.fetchForLocation == nil {
+		cachedProvider.fetchForLocation = map[string]*sync.Mutex{}
+	}
+	fetchMutex, found := cachedProvider.fetchForLocation[location.DisplayName]
+	if !found {
+		fetchMutex = &sync.Mutex{}
+		cachedProvider.fetchForLocation[location.DisplayName] = fetchMutex
+	}
+	return fetchMutex
+}
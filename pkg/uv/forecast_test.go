@@ -0,0 +1,146 @@
+package uv_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+type testForecastProvider struct {
+	FailOnLocation      map[string]bool
+	ForecastForLocation map[string]*uv.Forecast
+	ForecastedLocations []string
+}
+
+func (t *testForecastProvider) Forecast(locationToForecast *uv.Location) (*uv.Forecast, error) {
+	if t.FailOnLocation[locationToForecast.DisplayName] {
+		return nil, errors.New("something happened")
+	}
+	t.ForecastedLocations = append(t.ForecastedLocations, locationToForecast.DisplayName)
+	return t.ForecastForLocation[locationToForecast.DisplayName], nil
+}
+
+func TestOpenWeatherMap_Forecast(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/data/2.5/onecall" {
+			t.Errorf("Expected URL path %s but got %s", "/data/2.5/onecall", r.URL.Path)
+		}
+		if r.URL.Query().Get("exclude") != "current,minutely,alerts" {
+			t.Errorf("Expected exclude query param %s but got %s", "current,minutely,alerts", r.URL.Query().Get("exclude"))
+		}
+		json.NewEncoder(w).Encode(&uv.OneCallResponse{
+			Hourly: []*uv.OneCallHourly{{DT: 1624266000, UVI: 8.1, Clouds: 10}, {DT: 1624269600, UVI: 9.4}},
+			Daily:  []*uv.OneCallDaily{{DT: 1624266000, Sunrise: 1624241000, Sunset: 1624293000, UVI: 10.2}, {DT: 1624352400, UVI: 9.8}},
+		})
+	}))
+	defer server.Close()
+
+	openWeatherMap := &uv.OpenWeatherMap{Host: server.URL, AppID: "abcd"}
+	forecast, err := openWeatherMap.Forecast(uv.Locations[0])
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(forecast.Hourly) != 2 {
+		t.Errorf("Expected %d hourly forecasts but got %d", 2, len(forecast.Hourly))
+	}
+	if forecast.Hourly[1].UVIndex != 9.4 || forecast.Hourly[1].Time.Unix() != 1624269600 {
+		t.Errorf("Unexpected hourly forecast %+v", forecast.Hourly[1])
+	}
+	if forecast.Daily[0].MaxUVIndex != 10.2 || forecast.Daily[0].Sunset.Unix() != 1624293000 {
+		t.Errorf("Unexpected daily forecast %+v", forecast.Daily[0])
+	}
+	if !forecast.Daily[1].Sunrise.IsZero() || !forecast.Daily[1].Sunset.IsZero() {
+		t.Errorf("Expected a missing sunrise and sunset to be zero but got %+v", forecast.Daily[1])
+	}
+
+	hourly := forecast.HourlyBetween(time.Unix(1624266000, 0), time.Unix(1624269600, 0))
+	if len(hourly) != 1 || hourly[0].UVIndex != 8.1 {
+		t.Errorf("Unexpected hourly forecasts %+v", hourly)
+	}
+}
+
+func TestOpenWeatherMap_ForecastMissing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+	openWeatherMap := &uv.OpenWeatherMap{Host: server.URL, AppID: "abcd"}
+	if _, err := openWeatherMap.Forecast(uv.Locations[0]); err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestCachedForecastProvider(t *testing.T) {
+	now := time.Date(2021, time.June, 21, 9, 0, 0, 0, time.UTC)
+	provider := &testForecastProvider{ForecastForLocation: map[string]*uv.Forecast{"test": {}}}
+	cachedProvider := uv.NewCachedForecastProvider(provider, time.Hour)
+	cachedProvider.Now = func() time.Time { return now }
+	location := &uv.Location{DisplayName: "test"}
+
+	cachedProvider.Forecast(location)
+	now = now.Add(59 * time.Minute)
+	cachedProvider.Forecast(location)
+	if len(provider.ForecastedLocations) != 1 {
+		t.Errorf("Expected the forecast to be cached but it was fetched %d times", len(provider.ForecastedLocations))
+	}
+
+	now = now.Add(2 * time.Minute)
+	cachedProvider.Forecast(location)
+	if len(provider.ForecastedLocations) != 2 {
+		t.Errorf("Expected the forecast to expire but it was fetched %d times", len(provider.ForecastedLocations))
+	}
+}
+
+func TestCachedForecastProvider_DoesNotCacheErrors(t *testing.T) {
+	provider := &testForecastProvider{FailOnLocation: map[string]bool{"test": true}}
+	cachedProvider := uv.NewCachedForecastProvider(provider, time.Hour)
+	if _, err := cachedProvider.Forecast(&uv.Location{DisplayName: "test"}); err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+type blockingForecastProvider struct {
+	Release chan bool
+	Shared  *uv.Forecast
+}
+
+func (b *blockingForecastProvider) Forecast(locationToForecast *uv.Location) (*uv.Forecast, error) {
+	if locationToForecast.DisplayName == "slow" {
+		<-b.Release
+	}
+	return b.Shared, nil
+}
+
+func TestCachedForecastProvider_SlowLocation(t *testing.T) {
+	shared := &uv.Forecast{}
+	provider := &blockingForecastProvider{Release: make(chan bool), Shared: shared}
+	cachedProvider := uv.NewCachedForecastProvider(provider, time.Hour)
+
+	slowDone := make(chan bool)
+	go func() {
+		cachedProvider.Forecast(&uv.Location{DisplayName: "slow"})
+		slowDone <- true
+	}()
+
+	fastDone := make(chan bool)
+	go func() {
+		cachedProvider.Forecast(&uv.Location{DisplayName: "fast"})
+		fastDone <- true
+	}()
+	select {
+	case <-fastDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the forecast of another location not to wait for the slow location")
+	}
+	close(provider.Release)
+	<-slowDone
+
+	if !shared.FetchedAt.IsZero() {
+		t.Errorf("Expected the forecast of the wrapped provider not to be changed but its fetch time is %s", shared.FetchedAt)
+	}
+}
@@ -0,0 +1,390 @@
+package uv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// historyMaxGap is the longest time between two records that is still
+// attributed to a category, so that nights and outages are not counted.
+const historyMaxGap = 2 * time.Hour
+
+type HistoryRecord struct {
+	Location   string    `json:"location"`
+	ObservedAt time.Time `json:"observed_at"`
+	Source     string    `json:"source"`
+	UVIndex    float32   `json:"uv_index"`
+	RawUVIndex *float32  `json:"raw_uv_index,omitempty"`
+	Category   Category  `json:"category"`
+}
+
+var measurementsBucket = []byte("measurements")
+
+// History stores every measurement in an embedded bbolt database, in a bucket
+// per location keyed by observation time.
+type History struct {
+	db *bolt.DB
+}
+
+func OpenHistory(path string) (*History, error) {
+	db, openError := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if openError != nil {
+		return nil, fmt.Errorf("failed to open history database %s: %w", path, openError)
+	}
+	return &History{db: db}, nil
+}
+
+func (history *History) Close() error {
+	return history.db.Close()
+}
+
+func (history *History) Record(location *Location, measurement *Measurement) error {
+	return history.Store(&HistoryRecord{
+		Location:   location.DisplayName,
+		ObservedAt: measurement.ObservedAt,
+		Source:     measurement.Source,
+		UVIndex:    measurement.UVIndex,
+		RawUVIndex: measurement.RawUVIndex,
+		Category:   CategoryOf(measurement.UVIndex),
+	})
+}
+
+// Store writes the record, replacing any record of the same location that was
+// observed at the same time.
+func (history *History) Store(records ...*HistoryRecord) error {
+	return history.db.Update(func(tx *bolt.Tx) error {
+		for _, record := range records {
+			measurements, measurementsError := tx.CreateBucketIfNotExists(measurementsBucket)
+			if measurementsError != nil {
+				return fmt.Errorf("failed to create history bucket: %w", measurementsError)
+			}
+			bucket, bucketError := measurements.CreateBucketIfNotExists([]byte(record.Location))
+			if bucketError != nil {
+				return fmt.Errorf("failed to create history bucket for %s: %w", record.Location, bucketError)
+			}
+			value, jsonError := json.Marshal(record)
+			if jsonError != nil {
+				return fmt.Errorf("failed to encode history record: %w", jsonError)
+			}
+			if putError := bucket.Put(historyKey(record.ObservedAt), value); putError != nil {
+				return fmt.Errorf("failed to store history record of %s: %w", record.Location, putError)
+			}
+		}
+		return nil
+	})
+}
+
+// ImportHistory stores the records of a CSV archive of the location, such as
+// the past years that the climatology milestones are compared to. The header
+// names the observed_at column of RFC 3339 timestamps, the uv_index column and
+// an optional source column, which is "import" when missing. It returns the
+// number of imported records.
+func (history *History) ImportHistory(location *Location, archive io.Reader) (int, error) {
+	reader := csv.NewReader(archive)
+	header, headerError := reader.Read()
+	if headerError != nil {
+		return 0, fmt.Errorf("failed to read the archive header: %w", headerError)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[name] = i
+	}
+	observedAtColumn, observedAtFound := columns["observed_at"]
+	uvIndexColumn, uvIndexFound := columns["uv_index"]
+	if !observedAtFound || !uvIndexFound {
+		return 0, errors.New("the archive header must name the observed_at and uv_index columns")
+	}
+	sourceColumn, sourceFound := columns["source"]
+
+	records := []*HistoryRecord{}
+	for line := 2; ; line++ {
+		row, rowError := reader.Read()
+		if rowError == io.EOF {
+			break
+		}
+		if rowError != nil {
+			return 0, fmt.Errorf("failed to read line %d of the archive: %w", line, rowError)
+		}
+		observedAt, timeError := time.Parse(time.RFC3339, row[observedAtColumn])
+		if timeError != nil {
+			return 0, fmt.Errorf("failed to parse the observation time on line %d: %w", line, timeError)
+		}
+		uvIndex, parseError := strconv.ParseFloat(row[uvIndexColumn], 32)
+		if parseError != nil {
+			return 0, fmt.Errorf("failed to parse the UV index on line %d: %w", line, parseError)
+		}
+		if uvIndex < 0 {
+			return 0, fmt.Errorf("negative UV index %.1f on line %d", uvIndex, line)
+		}
+		source := "import"
+		if sourceFound && row[sourceColumn] != "" {
+			source = row[sourceColumn]
+		}
+		records = append(records, &HistoryRecord{
+			Location:   location.DisplayName,
+			ObservedAt: observedAt,
+			Source:     source,
+			UVIndex:    float32(uvIndex),
+			Category:   CategoryOf(float32(uvIndex)),
+		})
+	}
+	if storeError := history.Store(records...); storeError != nil {
+		return 0, storeError
+	}
+	return len(records), nil
+}
+
+// Range returns the records of the location observed from start (inclusive)
+// until end (exclusive), sorted by observation time.
+func (history *History) Range(location *Location, start time.Time, end time.Time) ([]*HistoryRecord, error) {
+	records := []*HistoryRecord{}
+	viewError := history.db.View(func(tx *bolt.Tx) error {
+		measurements := tx.Bucket(measurementsBucket)
+		if measurements == nil {
+			return nil
+		}
+		bucket := measurements.Bucket([]byte(location.DisplayName))
+		if bucket == nil {
+			return nil
+		}
+		cursor := bucket.Cursor()
+		endKey := historyKey(end)
+		for key, value := cursor.Seek(historyKey(start)); key != nil && bytes.Compare(key, endKey) < 0; key, value = cursor.Next() {
+			record := &HistoryRecord{}
+			if jsonError := json.Unmarshal(value, record); jsonError != nil {
+				return fmt.Errorf("failed to decode history record: %w", jsonError)
+			}
+			records = append(records, record)
+		}
+		return nil
+	})
+	if viewError != nil {
+		return nil, fmt.Errorf("failed to read the history of %s: %w", location.DisplayName, viewError)
+	}
+	return records, nil
+}
+
+// DailyExtremes holds the highest and lowest UV index of a local date.
+type DailyExtremes struct {
+	Date        time.Time
+	MaxUVIndex  float32
+	MaxTime     time.Time
+	MinUVIndex  float32
+	RecordCount int
+}
+
+// DailyExtremes returns the extremes of every local date from start until end
+// that has any records, sorted by date.
+func (history *History) DailyExtremes(location *Location, start time.Time, end time.Time) ([]*DailyExtremes, error) {
+	timeZone, timeZoneError := GetLocation(location.IANA)
+	if timeZoneError != nil {
+		return nil, timeZoneError
+	}
+	records, rangeError := history.Range(location, start, end)
+	if rangeError != nil {
+		return nil, rangeError
+	}
+	extremesByDate := map[string]*DailyExtremes{}
+	for _, record := range records {
+		localObservedAt := record.ObservedAt.In(timeZone)
+		date := localObservedAt.Format("2006-01-02")
+		extremes, found := extremesByDate[date]
+		if !found {
+			extremes = &DailyExtremes{
+				Date:       time.Date(localObservedAt.Year(), localObservedAt.Month(), localObservedAt.Day(), 0, 0, 0, 0, timeZone),
+				MaxUVIndex: record.UVIndex,
+				MaxTime:    localObservedAt,
+				MinUVIndex: record.UVIndex,
+			}
+			extremesByDate[date] = extremes
+		}
+		if record.UVIndex > extremes.MaxUVIndex {
+			extremes.MaxUVIndex = record.UVIndex
+			extremes.MaxTime = localObservedAt
+		}
+		if record.UVIndex < extremes.MinUVIndex {
+			extremes.MinUVIndex = record.UVIndex
+		}
+		extremes.RecordCount++
+	}
+	dailyExtremes := []*DailyExtremes{}
+	for _, extremes := range extremesByDate {
+		dailyExtremes = append(dailyExtremes, extremes)
+	}
+	sort.Slice(dailyExtremes, func(i, j int) bool {
+		return dailyExtremes[i].Date.Before(dailyExtremes[j].Date)
+	})
+	return dailyExtremes, nil
+}
+
+// Climatology is the average daily peak UV index of a calendar month over
+// past years.
+type Climatology struct {
+	Month            time.Month
+	Years            int
+	Days             int
+	AverageDailyPeak float32
+}
+
+// MonthlyClimatology averages the daily peaks of the month in each of the
+// years preceding the local year of before. Years without records of the month
+// are skipped, so Years may be lower than years.
+func (history *History) MonthlyClimatology(location *Location, month time.Month, before time.Time, years int) (*Climatology, error) {
+	timeZone, timeZoneError := GetLocation(location.IANA)
+	if timeZoneError != nil {
+		return nil, timeZoneError
+	}
+	climatology := &Climatology{Month: month}
+	var peakSum float32
+	for year := before.In(timeZone).Year() - years; year < before.In(timeZone).Year(); year++ {
+		start := time.Date(year, month, 1, 0, 0, 0, 0, timeZone)
+		dailyExtremes, extremesError := history.DailyExtremes(location, start, start.AddDate(0, 1, 0))
+		if extremesError != nil {
+			return nil, extremesError
+		}
+		if len(dailyExtremes) == 0 {
+			continue
+		}
+		climatology.Years++
+		for _, extremes := range dailyExtremes {
+			peakSum += extremes.MaxUVIndex
+			climatology.Days++
+		}
+	}
+	if climatology.Days > 0 {
+		climatology.AverageDailyPeak = peakSum / float32(climatology.Days)
+	}
+	return climatology, nil
+}
+
+// CategoryDurations sums how long the UV index stayed in each category from
+// start until end. The time between two records is attributed to the category
+// of the first one, unless they are more than historyMaxGap apart.
+func (history *History) CategoryDurations(location *Location, start time.Time, end time.Time) (map[Category]time.Duration, error) {
+	records, rangeError := history.Range(location, start, end)
+	if rangeError != nil {
+		return nil, rangeError
+	}
+	durations := map[Category]time.Duration{}
+	for i := 1; i < len(records); i++ {
+		gap := records[i].ObservedAt.Sub(records[i-1].ObservedAt)
+		if gap > historyMaxGap {
+			continue
+		}
+		durations[records[i-1].Category] += gap
+	}
+	return durations, nil
+}
+
+// RetentionPolicy keeps records at full resolution for FullResolution, then
+// keeps only the highest record of every DownsampleInterval so that daily
+// peaks survive, and deletes records older than MaxAge. Zero values disable
+// the respective step.
+type RetentionPolicy struct {
+	FullResolution     time.Duration
+	DownsampleInterval time.Duration
+	MaxAge             time.Duration
+}
+
+func (history *History) ApplyRetention(policy *RetentionPolicy, now time.Time) error {
+	return history.db.Update(func(tx *bolt.Tx) error {
+		measurements := tx.Bucket(measurementsBucket)
+		if measurements == nil {
+			return nil
+		}
+		return measurements.ForEach(func(name []byte, _ []byte) error {
+			bucket := measurements.Bucket(name)
+			if policy.MaxAge > 0 {
+				if deleteError := deleteBefore(bucket, historyKey(now.Add(-policy.MaxAge))); deleteError != nil {
+					return fmt.Errorf("failed to delete old history of %s: %w", string(name), deleteError)
+				}
+			}
+			if policy.FullResolution > 0 && policy.DownsampleInterval > 0 {
+				if downsampleError := downsample(bucket, historyKey(now.Add(-policy.FullResolution)), policy.DownsampleInterval); downsampleError != nil {
+					return fmt.Errorf("failed to downsample history of %s: %w", string(name), downsampleError)
+				}
+			}
+			return nil
+		})
+	})
+}
+
+func deleteBefore(bucket *bolt.Bucket, endKey []byte) error {
+	keys := [][]byte{}
+	cursor := bucket.Cursor()
+	for key, _ := cursor.First(); key != nil && bytes.Compare(key, endKey) < 0; key, _ = cursor.Next() {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		if deleteError := bucket.Delete(key); deleteError != nil {
+			return deleteError
+		}
+	}
+	return nil
+}
+
+func downsample(bucket *bolt.Bucket, endKey []byte, interval time.Duration) error {
+	keysToDelete := [][]byte{}
+	var keptKey []byte
+	var keptUVIndex float32
+	var keptSlot int64
+	cursor := bucket.Cursor()
+	for key, value := cursor.First(); key != nil && bytes.Compare(key, endKey) < 0; key, value = cursor.Next() {
+		record := &HistoryRecord{}
+		if jsonError := json.Unmarshal(value, record); jsonError != nil {
+			return jsonError
+		}
+		slot := record.ObservedAt.UnixNano() / int64(interval)
+		if keptKey == nil || slot != keptSlot {
+			keptKey, keptUVIndex, keptSlot = key, record.UVIndex, slot
+			continue
+		}
+		if record.UVIndex > keptUVIndex {
+			keysToDelete = append(keysToDelete, keptKey)
+			keptKey, keptUVIndex = key, record.UVIndex
+			continue
+		}
+		keysToDelete = append(keysToDelete, key)
+	}
+	for _, key := range keysToDelete {
+		if deleteError := bucket.Delete(key); deleteError != nil {
+			return deleteError
+		}
+	}
+	return nil
+}
+
+func historyKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
+
+// HistoryRetentionTask applies the retention policy once a day.
+type HistoryRetentionTask struct {
+	History *History
+	Policy  *RetentionPolicy
+
+	lastRun time.Time
+}
+
+func (task *HistoryRetentionTask) Run(now time.Time) error {
+	if !task.lastRun.IsZero() && now.Sub(task.lastRun) < 24*time.Hour {
+		return nil
+	}
+	task.lastRun = now
+	if retentionError := task.History.ApplyRetention(task.Policy, now); retentionError != nil {
+		return fmt.Errorf("failed to apply the history retention policy: %w", retentionError)
+	}
+	return nil
+}
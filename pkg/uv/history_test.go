@@ -0,0 +1,165 @@
+package uv_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func openTestHistory(t *testing.T) *uv.History {
+	history, err := uv.OpenHistory(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { history.Close() })
+	return history
+}
+
+func recordHistory(t *testing.T, history *uv.History, location *uv.Location, start time.Time, interval time.Duration, uvIndices ...float32) {
+	for i, uvIndex := range uvIndices {
+		measurement := &uv.Measurement{ObservedAt: start.Add(time.Duration(i) * interval), Source: "test", UVIndex: uvIndex}
+		if err := history.Record(location, measurement); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHistory_Range(t *testing.T) {
+	history := openTestHistory(t)
+	start := time.Date(2021, time.June, 21, 9, 0, 0, 0, time.UTC)
+	recordHistory(t, history, uv.TelAviv, start, 10*time.Minute, 5, 6, 7, 8)
+	recordHistory(t, history, &uv.Location{DisplayName: "Haifa"}, start, 10*time.Minute, 1, 2)
+
+	records, err := history.Range(uv.TelAviv, start.Add(10*time.Minute), start.Add(30*time.Minute))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected %d records but got %d", 2, len(records))
+	}
+	if records[0].UVIndex != 6 || records[1].UVIndex != 7 {
+		t.Errorf("Unexpected records %+v %+v", records[0], records[1])
+	}
+	if records[1].Category != uv.CategoryHigh || records[1].Source != "test" || records[1].Location != "Tel-Aviv" {
+		t.Errorf("Unexpected record %+v", records[1])
+	}
+
+	records, _ = history.Range(&uv.Location{DisplayName: "Atlantis"}, start, start.Add(time.Hour))
+	if len(records) != 0 {
+		t.Errorf("Expected no records of an unknown location but got %d", len(records))
+	}
+}
+
+func TestHistory_ImportHistory(t *testing.T) {
+	history := openTestHistory(t)
+	archive := "uv_index,observed_at,source\n4.5,2019-06-21T09:00:00Z,ims\n9,2019-06-21T10:00:00Z,\n"
+	imported, err := history.ImportHistory(uv.TelAviv, strings.NewReader(archive))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if imported != 2 {
+		t.Errorf("Expected %d imported records but got %d", 2, imported)
+	}
+	start := time.Date(2019, time.June, 21, 0, 0, 0, 0, time.UTC)
+	records, _ := history.Range(uv.TelAviv, start, start.AddDate(0, 0, 1))
+	if len(records) != 2 {
+		t.Fatalf("Expected %d records but got %d", 2, len(records))
+	}
+	if records[0].UVIndex != 4.5 || records[0].Source != "ims" || records[1].Category != uv.CategoryVeryHigh || records[1].Source != "import" {
+		t.Errorf("Unexpected records %+v %+v", records[0], records[1])
+	}
+
+	for _, invalid := range []string{
+		"time,uv\n2019-06-21T09:00:00Z,4.5\n",
+		"observed_at,uv_index\n2019-06-21 09:00,4.5\n",
+		"observed_at,uv_index\n2019-06-21T09:00:00Z,high\n",
+		"observed_at,uv_index\n2019-06-21T09:00:00Z,-9999\n",
+	} {
+		if _, err := history.ImportHistory(uv.TelAviv, strings.NewReader(invalid)); err == nil {
+			t.Errorf("Expected an error for the archive %q", invalid)
+		}
+	}
+}
+
+func TestHistory_DailyExtremes(t *testing.T) {
+	history := openTestHistory(t)
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	firstDay := time.Date(2021, time.June, 20, 10, 0, 0, 0, jerusalem)
+	recordHistory(t, history, uv.TelAviv, firstDay, time.Hour, 6, 9.5, 7)
+	recordHistory(t, history, uv.TelAviv, firstDay.AddDate(0, 0, 1), time.Hour, 5, 8, 10.2, 4)
+
+	dailyExtremes, err := history.DailyExtremes(uv.TelAviv, firstDay.AddDate(0, 0, -1), firstDay.AddDate(0, 0, 2))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(dailyExtremes) != 2 {
+		t.Fatalf("Expected %d days but got %d", 2, len(dailyExtremes))
+	}
+	if dailyExtremes[0].MaxUVIndex != 9.5 || dailyExtremes[0].MinUVIndex != 6 || dailyExtremes[0].RecordCount != 3 {
+		t.Errorf("Unexpected extremes %+v", dailyExtremes[0])
+	}
+	if dailyExtremes[1].MaxUVIndex != 10.2 || dailyExtremes[1].MaxTime.Format("15:04") != "12:00" || dailyExtremes[1].MinUVIndex != 4 {
+		t.Errorf("Unexpected extremes %+v", dailyExtremes[1])
+	}
+}
+
+func TestHistory_CategoryDurations(t *testing.T) {
+	history := openTestHistory(t)
+	start := time.Date(2021, time.June, 21, 9, 0, 0, 0, time.UTC)
+	recordHistory(t, history, uv.TelAviv, start, 30*time.Minute, 4, 7, 9)
+	recordHistory(t, history, uv.TelAviv, start.Add(6*time.Hour), 30*time.Minute, 2, 1)
+
+	durations, err := history.CategoryDurations(uv.TelAviv, start, start.Add(24*time.Hour))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if durations[uv.CategoryModerate] != 30*time.Minute || durations[uv.CategoryHigh] != 30*time.Minute {
+		t.Errorf("Unexpected durations %v", durations)
+	}
+	if durations[uv.CategoryVeryHigh] != 0 {
+		t.Errorf("Expected the gap after the last Very High record to be ignored but got %s", durations[uv.CategoryVeryHigh])
+	}
+	if durations[uv.CategoryLow] != 30*time.Minute {
+		t.Errorf("Expected 30 minutes of Low but got %s", durations[uv.CategoryLow])
+	}
+}
+
+func TestHistory_ApplyRetention(t *testing.T) {
+	history := openTestHistory(t)
+	now := time.Date(2021, time.June, 21, 12, 0, 0, 0, time.UTC)
+	recordHistory(t, history, uv.TelAviv, now.AddDate(0, 0, -400), 10*time.Minute, 3, 4)
+	recordHistory(t, history, uv.TelAviv, now.AddDate(0, 0, -40), 10*time.Minute, 5, 8, 6, 2, 3, 4, 7)
+	recordHistory(t, history, uv.TelAviv, now.Add(-time.Hour), 10*time.Minute, 5, 6, 7)
+
+	policy := &uv.RetentionPolicy{FullResolution: 30 * 24 * time.Hour, DownsampleInterval: time.Hour, MaxAge: 365 * 24 * time.Hour}
+	if err := history.ApplyRetention(policy, now); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	records, _ := history.Range(uv.TelAviv, now.AddDate(-2, 0, 0), now)
+	if len(records) != 5 {
+		t.Fatalf("Expected %d records but got %d", 5, len(records))
+	}
+	if records[0].UVIndex != 8 || records[1].UVIndex != 7 {
+		t.Errorf("Expected downsampling to keep the hourly peaks but got %.1f and %.1f", records[0].UVIndex, records[1].UVIndex)
+	}
+}
+
+func TestHistoryRetentionTask(t *testing.T) {
+	history := openTestHistory(t)
+	now := time.Date(2021, time.June, 21, 12, 0, 0, 0, time.UTC)
+	task := &uv.HistoryRetentionTask{History: history, Policy: &uv.RetentionPolicy{MaxAge: time.Hour}}
+
+	recordHistory(t, history, uv.TelAviv, now.Add(-2*time.Hour), time.Minute, 5)
+	task.Run(now)
+	recordHistory(t, history, uv.TelAviv, now.Add(-2*time.Hour), time.Minute, 5)
+	task.Run(now.Add(time.Hour))
+
+	records, _ := history.Range(uv.TelAviv, now.AddDate(0, 0, -1), now)
+	if len(records) != 1 {
+		t.Errorf("Expected the retention policy to run once a day but %d records remain", len(records))
+	}
+}
@@ -2,17 +2,24 @@ package uv
 
 import (
 	"fmt"
+	"math"
+	"strings"
 	"time"
 )
 
 type Location struct {
 	DisplayName string
 	IANA        string
-	Latitude    string
-	Longitude   string
+	// Latitude and Longitude are in decimal degrees
+	Latitude  float64
+	Longitude float64
+	// Elevation is in meters above sea level, zero when unknown
+	Elevation float64
+	// Surface is the ground around the location, which reflects UV
+	Surface Surface
 }
 
-var TelAviv = &Location{DisplayName: "Tel-Aviv", IANA: "Asia/Jerusalem", Latitude: "32.109333", Longitude: "34.855499"}
+var TelAviv = &Location{DisplayName: "Tel-Aviv", IANA: "Asia/Jerusalem", Latitude: 32.109333, Longitude: 34.855499}
 
 var Locations = []*Location{
 	TelAviv,
@@ -24,4 +31,68 @@ func GetLocation(iana string) (*time.Location, error) {
 		return nil, fmt.Errorf("failed to load location %s: %w", iana, locationError)
 	}
 	return location, nil
-}
\ No newline at end of file
+}
+
+// Coordinates returns the latitude and longitude of the location, and fails
+// when they are out of range.
+func (location *Location) Coordinates() (float64, float64, error) {
+	if math.IsNaN(location.Latitude) || location.Latitude < -90 || location.Latitude > 90 {
+		return 0, 0, fmt.Errorf("latitude %v of %s is not between -90 and 90", location.Latitude, location.DisplayName)
+	}
+	if math.IsNaN(location.Longitude) || location.Longitude < -180 || location.Longitude > 180 {
+		return 0, 0, fmt.Errorf("longitude %v of %s is not between -180 and 180", location.Longitude, location.DisplayName)
+	}
+	return location.Latitude, location.Longitude, nil
+}
+
+// Validate checks that the location has a name, coordinates in range and a
+// time zone that can be loaded.
+func (location *Location) Validate() error {
+	if strings.TrimSpace(location.DisplayName) == "" {
+		return fmt.Errorf("location at %v,%v has no display name", location.Latitude, location.Longitude)
+	}
+	if _, _, coordinatesError := location.Coordinates(); coordinatesError != nil {
+		return coordinatesError
+	}
+	// time.LoadLocation treats an empty name as UTC
+	if location.IANA == "" {
+		return fmt.Errorf("%s has no IANA time zone", location.DisplayName)
+	}
+	if _, timeZoneError := GetLocation(location.IANA); timeZoneError != nil {
+		return fmt.Errorf("invalid time zone of %s: %w", location.DisplayName, timeZoneError)
+	}
+	return nil
+}
+
+// ValidateLocations validates every location and checks that no two locations
+// share a display name, since the display name keys their state.
+func ValidateLocations(locations []*Location) error {
+	problems := []string{}
+	seen := map[string]bool{}
+	for i, location := range locations {
+		if location == nil {
+			problems = append(problems, fmt.Sprintf("location %d is nil", i))
+			continue
+		}
+		if validationError := location.Validate(); validationError != nil {
+			problems = append(problems, validationError.Error())
+		}
+		if seen[location.DisplayName] {
+			problems = append(problems, fmt.Sprintf("more than one location is named %s", location.DisplayName))
+		}
+		seen[location.DisplayName] = true
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid locations: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func FindLocation(displayName string) (*Location, error) {
+	for _, location := range Locations {
+		if location.DisplayName == displayName {
+			return location, nil
+		}
+	}
+	return nil, fmt.Errorf("no location is named %s", displayName)
+}
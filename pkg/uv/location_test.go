@@ -15,11 +15,11 @@ func TestLocations(t *testing.T) {
 	if firstLocation.IANA != "Asia/Jerusalem" {
 		t.Errorf("Expected IANA to be %s but got %s", "Asia/Jerusalem", firstLocation.IANA)
 	}
-	if firstLocation.Latitude != "32.109333" {
-		t.Errorf("Expected Latitude to be %s but got %s", "32.109333", firstLocation.Latitude)
+	if firstLocation.Latitude != 32.109333 {
+		t.Errorf("Expected Latitude to be %f but got %f", 32.109333, firstLocation.Latitude)
 	}
-	if firstLocation.Longitude != "34.855499" {
-		t.Errorf("Expected Latitude to be %s but got %s", "34.855499", firstLocation.Longitude)
+	if firstLocation.Longitude != 34.855499 {
+		t.Errorf("Expected Longitude to be %f but got %f", 34.855499, firstLocation.Longitude)
 	}
 }
 
@@ -45,3 +45,44 @@ func TestGetLocationOrFail_Invalid(t *testing.T) {
 		t.Errorf("Expected error message %s but got %s", "failed to load location haha: unknown time zone haha", err.Error())
 	}
 }
+
+func TestFindLocation(t *testing.T) {
+	location, err := uv.FindLocation("Tel-Aviv")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if location != uv.TelAviv {
+		t.Error("Expected to find Tel-Aviv")
+	}
+	if _, err := uv.FindLocation("Atlantis"); err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestValidateLocations(t *testing.T) {
+	if err := uv.ValidateLocations(uv.Locations); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		name      string
+		locations []*uv.Location
+		expected  string
+	}{
+		{"latitude out of range", []*uv.Location{{DisplayName: "test", IANA: "Asia/Jerusalem", Latitude: 222.222, Longitude: 34.85}}, "invalid locations: latitude 222.222 of test is not between -90 and 90"},
+		{"longitude out of range", []*uv.Location{{DisplayName: "test", IANA: "Asia/Jerusalem", Latitude: 32.1, Longitude: -333.333}}, "invalid locations: longitude -333.333 of test is not between -180 and 180"},
+		{"unknown time zone", []*uv.Location{{DisplayName: "test", IANA: "Continent/City", Latitude: 32.1, Longitude: 34.85}}, "invalid locations: invalid time zone of test: failed to load location Continent/City: unknown time zone Continent/City"},
+		{"missing time zone", []*uv.Location{{DisplayName: "test", Latitude: 32.1, Longitude: 34.85}}, "invalid locations: test has no IANA time zone"},
+		{"nil location", []*uv.Location{uv.TelAviv, nil}, "invalid locations: location 1 is nil"},
+		{"missing display name", []*uv.Location{{IANA: "Asia/Jerusalem", Latitude: 32.1, Longitude: 34.85}}, "invalid locations: location at 32.1,34.85 has no display name"},
+		{"duplicate display names", []*uv.Location{uv.TelAviv, {DisplayName: "Tel-Aviv", IANA: "Asia/Jerusalem", Latitude: 32.08, Longitude: 34.78}}, "invalid locations: more than one location is named Tel-Aviv"},
+	}
+	for _, testCase := range cases {
+		err := uv.ValidateLocations(testCase.locations)
+		if err == nil {
+			t.Errorf("%s: expected an error", testCase.name)
+		} else if err.Error() != testCase.expected {
+			t.Errorf("%s: expected error message %s but got %s", testCase.name, testCase.expected, err.Error())
+		}
+	}
+}
@@ -1,11 +1,17 @@
 package uv
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/dghubble/go-twitter/twitter"
@@ -15,13 +21,31 @@ import (
 var latestIndexForLocation = map[string]float32{}
 var lastPoll time.Time
 
+var latestMeasurementMutex sync.Mutex
+var latestMeasurementForLocation = map[string]*Measurement{}
+
+// LatestMeasurement returns the most recent measurement of the location, even
+// if it did not change its severity, or nil if it was never measured.
+func LatestMeasurement(location *Location) *Measurement {
+	latestMeasurementMutex.Lock()
+	defer latestMeasurementMutex.Unlock()
+	return latestMeasurementForLocation[location.DisplayName]
+}
+
 type MeasurementSettings struct {
 	ExitChan     <-chan bool
 	LoopInterval time.Duration
 	PollInterval time.Duration
+	// AdaptivePolling replaces the fixed PollInterval with an interval per
+	// location when set, and PollInterval is only used after failures
+	AdaptivePolling *AdaptivePolling
 }
 
 func MeasureAndReport(measurerReporter MeasurerReporter, measurementSettings *MeasurementSettings) {
+	var poller *adaptivePoller
+	if measurementSettings.AdaptivePolling != nil {
+		poller = newAdaptivePoller(measurementSettings.AdaptivePolling, measurementSettings.PollInterval)
+	}
 Loop:
 	for {
 		select {
@@ -29,7 +53,9 @@ Loop:
 			log.Println("Received exit signal")
 			break Loop
 		default:
-			if lastPoll.IsZero() || time.Since(lastPoll) >= measurementSettings.PollInterval {
+			if poller != nil {
+				poller.poll(Locations, measurerReporter, time.Now())
+			} else if lastPoll.IsZero() || time.Since(lastPoll) >= measurementSettings.PollInterval {
 				log.Println("Measuring UV index")
 				MeasureAndReportLocations(Locations, measurerReporter)
 				lastPoll = time.Now()
@@ -52,69 +78,210 @@ func MeasureAndReportLocations(locations []*Location, measurerReporter MeasurerR
 	}
 }
 
-func GetMeasureAndReportFunction(measurementProvider MeasurementProvider, reporter MeasurementReporter) MeasurerReporter {
+// MeasurementRecorder is handed every measurement, whether or not it changed
+// the severity of the UV index.
+type MeasurementRecorder interface {
+	Record(location *Location, measurement *Measurement) error
+}
+
+func GetMeasureAndReportFunction(measurementProvider MeasurementProvider, reporter MeasurementReporter, recorders ...MeasurementRecorder) MeasurerReporter {
 	return func(location *Location) error {
-		uvIndex, measurementError := measurementProvider.Measure(location)
+		measurement, measurementError := measurementProvider.Measure(location)
 		if measurementError != nil {
 			return fmt.Errorf("failed to get UV index for %s: %w", location.DisplayName, measurementError)
 		}
+		if measurement.ObservedAt.IsZero() {
+			measurement.ObservedAt = time.Now()
+		}
+		for _, recorder := range recorders {
+			if recordError := recorder.Record(location, measurement); recordError != nil {
+				log.Println(fmt.Errorf("failed to record UV index for %s: %w", location.DisplayName, recordError))
+			}
+		}
+		latestMeasurementMutex.Lock()
+		latestMeasurementForLocation[location.DisplayName] = measurement
+		latestMeasurementMutex.Unlock()
 		lastMeasurement := latestIndexForLocation[location.DisplayName]
-		severityChangedSinceLastMeasurement := IndexHasChanged(lastMeasurement, uvIndex)
+		severityChangedSinceLastMeasurement := IndexHasChanged(lastMeasurement, measurement.UVIndex)
 		if severityChangedSinceLastMeasurement {
-			reportError := reporter.Report(location, uvIndex)
+			reportError := reporter.Report(location, measurement)
 			if reportError != nil {
 				return fmt.Errorf("failed to report UV index for %s: %w", location.DisplayName, reportError)
 			}
-			latestIndexForLocation[location.DisplayName] = uvIndex
+			latestIndexForLocation[location.DisplayName] = measurement.UVIndex
+		}
+		return nil
+	}
+}
+
+// DefaultNightSolarElevation is the solar elevation in degrees below which the
+// UV index is practically zero.
+const DefaultNightSolarElevation = 2.0
+
+type DaylightSettings struct {
+	// MinSolarElevation is the solar elevation in degrees below which a
+	// location is not measured
+	MinSolarElevation float64
+	Now               func() time.Time
+}
+
+// GetDaylightAwareFunction skips measuring a location while the sun is below
+// the minimum elevation, and resumes once it rises above it. Nothing is
+// measured or recorded during the night, so the latest measurement stays the
+// last one of the day.
+func GetDaylightAwareFunction(measurerReporter MeasurerReporter, daylightSettings *DaylightSettings) MeasurerReporter {
+	nightForLocation := map[string]bool{}
+	return func(location *Location) error {
+		now := time.Now()
+		if daylightSettings.Now != nil {
+			now = daylightSettings.Now()
+		}
+		position, positionError := location.SolarPosition(now)
+		if positionError != nil {
+			return fmt.Errorf("failed to compute the solar position of %s: %w", location.DisplayName, positionError)
+		}
+		if position.Elevation >= daylightSettings.MinSolarElevation {
+			nightForLocation[location.DisplayName] = false
+			return measurerReporter(location)
+		}
+		if nightForLocation[location.DisplayName] {
+			return nil
+		}
+		nightForLocation[location.DisplayName] = true
+
+		resumeDay := now
+		if solarDay, solarDayError := location.SolarDay(now); solarDayError == nil && now.After(solarDay.SolarNoon) {
+			resumeDay = now.AddDate(0, 0, 1)
+		}
+		if solarDay, solarDayError := location.SolarDay(resumeDay); solarDayError == nil && !solarDay.Sunrise.IsZero() {
+			log.Printf("Pausing measurements for the night in %s, measuring resumes after sunrise at %s\n", location.DisplayName, solarDay.Sunrise.Format("15:04"))
+		} else {
+			log.Printf("Pausing measurements for the night in %s\n", location.DisplayName)
 		}
 		return nil
 	}
 }
 
 type OneCallCurrent struct {
-	UVI float32 `json:"uvi"`
+	DT      int64   `json:"dt"`
+	Sunrise int64   `json:"sunrise"`
+	Sunset  int64   `json:"sunset"`
+	Clouds  float32 `json:"clouds"`
+	UVI     float32 `json:"uvi"`
+}
+
+type OneCallHourly struct {
+	DT     int64   `json:"dt"`
+	Clouds float32 `json:"clouds"`
+	UVI    float32 `json:"uvi"`
+}
+
+type OneCallDaily struct {
+	DT      int64   `json:"dt"`
+	Sunrise int64   `json:"sunrise"`
+	Sunset  int64   `json:"sunset"`
+	UVI     float32 `json:"uvi"`
 }
 
 type OneCallResponse struct {
-	Current *OneCallCurrent `json:"current"`
+	Current *OneCallCurrent  `json:"current"`
+	Hourly  []*OneCallHourly `json:"hourly,omitempty"`
+	Daily   []*OneCallDaily  `json:"daily,omitempty"`
+}
+
+// Measurement is a single UV reading. Optional values are nil and unknown
+// times are zero when the provider does not supply them.
+type Measurement struct {
+	ObservedAt      time.Time
+	Source          string
+	UVIndex         float32
+	ClearSkyUVIndex *float32
+	CloudCover      *float32
+	Ozone           *float32
+	Sunrise         time.Time
+	Sunset          time.Time
+	Uncertainty     *float32
+	// RawUVIndex is the provider's value before the elevation and surface
+	// correction of the location, nil when the value was not corrected
+	RawUVIndex *float32
 }
 
 type MeasurementProvider interface {
-	Measure(locationToMeasure *Location) (float32, error)
+	Measure(locationToMeasure *Location) (*Measurement, error)
 }
 
+var ErrRateLimited = errors.New("the provider rate limited the request")
+
 type OpenWeatherMap struct {
 	Host  string
 	AppID string
 }
 
-func (openweathermap *OpenWeatherMap) Measure(locationToPoll *Location) (float32, error) {
+func (openweathermap *OpenWeatherMap) Measure(locationToPoll *Location) (*Measurement, error) {
+	ocr := OneCallResponse{}
+	callError := openweathermap.oneCall("/data/2.5/onecall", locationToPoll, map[string]string{"exclude": "minutely,hourly,alerts,daily"}, &ocr)
+	if callError != nil {
+		return nil, callError
+	}
+	if ocr.Current == nil {
+		return nil, errors.New("response is missing the current weather")
+	}
+	cloudCover := ocr.Current.Clouds
+	return &Measurement{
+		ObservedAt: unixTime(ocr.Current.DT),
+		Source:     "openweathermap",
+		UVIndex:    ocr.Current.UVI,
+		CloudCover: &cloudCover,
+		Sunrise:    unixTime(ocr.Current.Sunrise),
+		Sunset:     unixTime(ocr.Current.Sunset),
+	}, nil
+}
+
+// unixTime converts seconds since the Unix epoch, and returns the zero time for
+// fields missing from a response instead of 1970-01-01.
+func unixTime(seconds int64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+func (openweathermap *OpenWeatherMap) oneCall(path string, locationToPoll *Location, params map[string]string, response interface{}) error {
 	client := http.DefaultClient
 
-	req, requestError := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/data/2.5/onecall", openweathermap.Host), nil)
+	req, requestError := http.NewRequest(http.MethodGet, fmt.Sprintf("%s%s", openweathermap.Host, path), nil)
 	if requestError != nil {
-		return 0, fmt.Errorf("failed to prepare HTTP request: %w", requestError)
+		return fmt.Errorf("failed to prepare HTTP request: %w", requestError)
 	}
 
 	q := req.URL.Query()
 
-	q.Add("lat", locationToPoll.Latitude)
-	q.Add("lon", locationToPoll.Longitude)
+	q.Add("lat", strconv.FormatFloat(locationToPoll.Latitude, 'f', -1, 64))
+	q.Add("lon", strconv.FormatFloat(locationToPoll.Longitude, 'f', -1, 64))
 
 	q.Add("appid", openweathermap.AppID)
-	q.Add("exclude", "minutely,hourly,alerts,daily")
+	for key, value := range params {
+		q.Add(key, value)
+	}
 	req.URL.RawQuery = q.Encode()
 	resp, requestExecError := client.Do(req)
 	if requestExecError != nil {
-		return 0, fmt.Errorf("failed to execute HTTP request: %w", requestExecError)
+		return fmt.Errorf("failed to execute HTTP request: %w", requestExecError)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return ErrRateLimited
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("request failed. Response code: %d. Body: %s", resp.StatusCode, string(body))
 	}
 	dec := json.NewDecoder(resp.Body)
-	ocr := OneCallResponse{}
-	jsonErr := dec.Decode(&ocr)
+	jsonErr := dec.Decode(response)
 	if jsonErr != nil {
-		return 0, fmt.Errorf("failed to parse JSON response: %w", jsonErr)
+		return fmt.Errorf("failed to parse JSON response: %w", jsonErr)
 	}
-	return ocr.Current.UVI, nil
+	return nil
 }
 
 func IndexHasChanged(latestUVIndex float32, newIndex float32) bool {
@@ -131,23 +298,40 @@ func IndexHasChanged(latestUVIndex float32, newIndex float32) bool {
 }
 
 type MeasurementReporter interface {
-	Report(locationToReport *Location, uvIndex float32) error
+	Report(locationToReport *Location, measurement *Measurement) error
+}
+
+// MessageReporter posts messages that are not tied to a single measurement,
+// like scheduled briefings and summaries.
+type MessageReporter interface {
+	ReportMessage(locationToReport *Location, message string) error
 }
 
 type STDOutMeasurementReporter struct{}
 
-func (measurementReporter *STDOutMeasurementReporter) Report(locationToReport *Location, uvIndex float32) error {
-	alert := getAlert(locationToReport, uvIndex)
-	fmt.Println(alert)
+func (measurementReporter *STDOutMeasurementReporter) Report(locationToReport *Location, measurement *Measurement) error {
+	alert := getAlert(locationToReport, measurement)
+	return measurementReporter.ReportMessage(locationToReport, alert)
+}
+
+func (measurementReporter *STDOutMeasurementReporter) ReportMessage(locationToReport *Location, message string) error {
+	fmt.Println(message)
 	return nil
 }
 
+func (measurementReporter *STDOutMeasurementReporter) ReportMedia(locationToReport *Location, message string, media *Media) error {
+	if media != nil {
+		fmt.Printf("[%d bytes PNG: %s]\n", len(media.PNG), media.AltText)
+	}
+	return measurementReporter.ReportMessage(locationToReport, message)
+}
+
 func NewTwitterMeasurementReporter(twitterAuth *TwitterAuth) *TwitterMeasurementReporter {
 	config := oauth1.NewConfig(twitterAuth.ConsumerKey, twitterAuth.ConsumerSecret)
 	token := oauth1.NewToken(twitterAuth.AccessToken, twitterAuth.AccessSecret)
 	httpClient := config.Client(oauth1.NoContext, token)
 	client := twitter.NewClient(httpClient)
-	return &TwitterMeasurementReporter{client: client}
+	return &TwitterMeasurementReporter{client: client, httpClient: httpClient}
 }
 
 type TwitterAuth struct {
@@ -158,28 +342,133 @@ type TwitterAuth struct {
 }
 
 type TwitterMeasurementReporter struct {
-	client *twitter.Client
+	client     *twitter.Client
+	httpClient *http.Client
+}
+
+func (t *TwitterMeasurementReporter) Report(locationToReport *Location, measurement *Measurement) error {
+	alert := getAlert(locationToReport, measurement)
+	return t.ReportMessage(locationToReport, alert)
+}
+
+func (t *TwitterMeasurementReporter) ReportMessage(locationToReport *Location, message string) error {
+	_, response, tweetError := t.client.Statuses.Update(message, nil)
+	if tweetError != nil {
+		return fmt.Errorf("failed to tweet '%s': %w", message, tweetError)
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		body, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("failed to tweet '%s'. Response code: %d. Body: %s", message, response.StatusCode, string(body))
+	}
+	return nil
 }
 
-func (t *TwitterMeasurementReporter) Report(locationToReport *Location, uvIndex float32) error {
-	alert := getAlert(locationToReport, uvIndex)
-	_, response, tweetError := t.client.Statuses.Update(alert, nil)
+const twitterUploadURL = "https://upload.twitter.com/1.1/media"
+
+// twitterMaxAltTextLength is the longest alt text that Twitter accepts.
+const twitterMaxAltTextLength = 1000
+
+// ReportMedia tweets the message with the card, or without it when the upload
+// fails so that the alert still goes out.
+func (t *TwitterMeasurementReporter) ReportMedia(locationToReport *Location, message string, media *Media) error {
+	if media == nil {
+		return t.ReportMessage(locationToReport, message)
+	}
+	mediaID, uploadError := t.uploadMedia(media)
+	if uploadError != nil {
+		log.Println(fmt.Errorf("failed to upload the card of %s, tweeting without it: %w", locationToReport.DisplayName, uploadError))
+		return t.ReportMessage(locationToReport, message)
+	}
+	_, response, tweetError := t.client.Statuses.Update(message, &twitter.StatusUpdateParams{MediaIds: []int64{mediaID}})
 	if tweetError != nil {
-		return fmt.Errorf("failed to tweet '%s': %w", alert, tweetError)
+		return fmt.Errorf("failed to tweet '%s': %w", message, tweetError)
 	}
 	if response.StatusCode >= http.StatusBadRequest {
 		body, _ := ioutil.ReadAll(response.Body)
-		return fmt.Errorf("failed to tweet '%s'. Response code: %d. Body: %s", alert, response.StatusCode, string(body))
+		return fmt.Errorf("failed to tweet '%s'. Response code: %d. Body: %s", message, response.StatusCode, string(body))
 	}
 	return nil
 }
 
-func getAlert(locationToReport *Location, uvIndex float32) string {
+// uploadMedia uploads the image with its alt text and returns its media ID.
+func (t *TwitterMeasurementReporter) uploadMedia(media *Media) (int64, error) {
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, partError := writer.CreateFormFile("media", "card.png")
+	if partError != nil {
+		return 0, fmt.Errorf("failed to prepare media upload: %w", partError)
+	}
+	if _, writeError := part.Write(media.PNG); writeError != nil {
+		return 0, fmt.Errorf("failed to prepare media upload: %w", writeError)
+	}
+	if closeError := writer.Close(); closeError != nil {
+		return 0, fmt.Errorf("failed to prepare media upload: %w", closeError)
+	}
+	uploaded := struct {
+		MediaID       int64  `json:"media_id"`
+		MediaIDString string `json:"media_id_string"`
+	}{}
+	if uploadError := t.post(twitterUploadURL+"/upload.json", writer.FormDataContentType(), &form, &uploaded); uploadError != nil {
+		return 0, fmt.Errorf("failed to upload media: %w", uploadError)
+	}
+
+	altText := media.AltText
+	if len([]rune(altText)) > twitterMaxAltTextLength {
+		altText = string([]rune(altText)[:twitterMaxAltTextLength])
+	}
+	metadata, jsonError := json.Marshal(map[string]interface{}{
+		"media_id": uploaded.MediaIDString,
+		"alt_text": map[string]string{"text": altText},
+	})
+	if jsonError != nil {
+		return 0, fmt.Errorf("failed to encode media metadata: %w", jsonError)
+	}
+	if metadataError := t.post(twitterUploadURL+"/metadata/create.json", "application/json", bytes.NewReader(metadata), nil); metadataError != nil {
+		return 0, fmt.Errorf("failed to set the alt text of media %s: %w", uploaded.MediaIDString, metadataError)
+	}
+	return uploaded.MediaID, nil
+}
+
+func (t *TwitterMeasurementReporter) post(url string, contentType string, body io.Reader, response interface{}) error {
+	resp, postError := t.httpClient.Post(url, contentType, body)
+	if postError != nil {
+		return postError
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		responseBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("request failed. Response code: %d. Body: %s", resp.StatusCode, string(responseBody))
+	}
+	if response == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
+
+func getAlert(locationToReport *Location, measurement *Measurement) string {
 	alerts := AltertsByLocation[locationToReport.DisplayName]
-	if uvIndex < 3.0 {
-		return alerts.Low(uvIndex)
-	} else if uvIndex < 8.0 {
-		return alerts.Moderate(uvIndex)
+	if measurement.UVIndex > 0 && measurement.UVIndex < 3.0 {
+		if templates, found := TemplatesByLocation[locationToReport.DisplayName]; found && templates.VitaminDAlert != "" {
+			alert, renderError := RenderTemplate("vitamin D alert", templates.VitaminDAlert, NewVitaminDGuidance(locationToReport, measurement))
+			if renderError == nil {
+				return alert
+			}
+			log.Println(renderError)
+		}
+	}
+	if measurement.UVIndex >= 8.0 {
+		if templates, found := TemplatesByLocation[locationToReport.DisplayName]; found && templates.BurnTimeAlert != "" {
+			alert, renderError := RenderTemplate("burn time alert", templates.BurnTimeAlert, NewBurnTimeGuidance(locationToReport, measurement))
+			if renderError == nil {
+				return alert
+			}
+			log.Println(renderError)
+		}
+	}
+	if measurement.UVIndex < 3.0 {
+		return alerts.Low(measurement)
+	} else if measurement.UVIndex < 8.0 {
+		return alerts.Moderate(measurement)
 	}
-	return alerts.High(uvIndex)
+	return alerts.High(measurement)
 }
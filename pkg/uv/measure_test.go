@@ -22,12 +22,12 @@ type testMeasurementProvider struct {
 	MeasuredLocations      []string
 }
 
-func (t *testMeasurementProvider) Measure(locationToMeasure *uv.Location) (float32, error) {
+func (t *testMeasurementProvider) Measure(locationToMeasure *uv.Location) (*uv.Measurement, error) {
 	if t.FailOnLocation[locationToMeasure.DisplayName] {
-		return 0, errors.New("something happened")
+		return nil, errors.New("something happened")
 	}
 	t.MeasuredLocations = append(t.MeasuredLocations, locationToMeasure.DisplayName)
-	return t.MeasurementForLocation[locationToMeasure.DisplayName], nil
+	return &uv.Measurement{Source: "test", UVIndex: t.MeasurementForLocation[locationToMeasure.DisplayName]}, nil
 }
 
 type testMeasurementReporter struct {
@@ -35,11 +35,11 @@ type testMeasurementReporter struct {
 	ReportedLocations map[string]float32
 }
 
-func (t *testMeasurementReporter) Report(locationToReport *uv.Location, uvIndex float32) error {
+func (t *testMeasurementReporter) Report(locationToReport *uv.Location, measurement *uv.Measurement) error {
 	if t.FailOnLocation[locationToReport.DisplayName] {
 		return errors.New("something happened")
 	}
-	t.ReportedLocations[locationToReport.DisplayName] = uvIndex
+	t.ReportedLocations[locationToReport.DisplayName] = measurement.UVIndex
 	return nil
 }
 
@@ -76,8 +76,8 @@ func TestMeasureAndReport(t *testing.T) {
 
 func TestMeasureAndReportLocations(t *testing.T) {
 	locations := []*uv.Location{
-		{DisplayName: "test", IANA: "Continent/City", Latitude: "222.222", Longitude: "333.333"},
-		{DisplayName: "test2", IANA: "Continent/City2", Latitude: "444.444", Longitude: "555.555"},
+		{DisplayName: "test", IANA: "Continent/City", Latitude: 32.1, Longitude: 34.85},
+		{DisplayName: "test2", IANA: "Continent/City2", Latitude: 31.25, Longitude: 34.79},
 	}
 
 	measuredLocations := []string{}
@@ -99,8 +99,8 @@ func TestMeasureAndReportLocations(t *testing.T) {
 
 func TestMeasureAndReportLocations_FailOnFirst(t *testing.T) {
 	locations := []*uv.Location{
-		{DisplayName: "test", IANA: "Continent/City", Latitude: "222.222", Longitude: "333.333"},
-		{DisplayName: "test2", IANA: "Continent/City2", Latitude: "444.444", Longitude: "555.555"},
+		{DisplayName: "test", IANA: "Continent/City", Latitude: 32.1, Longitude: 34.85},
+		{DisplayName: "test2", IANA: "Continent/City2", Latitude: 31.25, Longitude: 34.79},
 	}
 
 	measuredLocations := []string{}
@@ -124,7 +124,7 @@ func TestMeasureAndReportFunction(t *testing.T) {
 	provider := &testMeasurementProvider{MeasurementForLocation: make(map[string]float32)}
 	provider.MeasurementForLocation["test"] = 11.3
 	reporter := &testMeasurementReporter{ReportedLocations: make(map[string]float32)}
-	location := &uv.Location{DisplayName: "test", IANA: "Continent/City", Latitude: "222.222", Longitude: "333.333"}
+	location := &uv.Location{DisplayName: "test", IANA: "Continent/City", Latitude: 32.1, Longitude: 34.85}
 	err := uv.GetMeasureAndReportFunction(provider, reporter)(location)
 	if err != nil {
 		t.Error(fmt.Errorf("Unexpected error: %w", err))
@@ -141,7 +141,7 @@ func TestMeasureAndReportFunction(t *testing.T) {
 func TestMeasureAndReportFunction_IndexSeverityChanged(t *testing.T) {
 	provider := &testMeasurementProvider{MeasurementForLocation: make(map[string]float32)}
 	reporter := &testMeasurementReporter{ReportedLocations: make(map[string]float32)}
-	location := &uv.Location{DisplayName: "test", IANA: "Continent/City", Latitude: "222.222", Longitude: "333.333"}
+	location := &uv.Location{DisplayName: "test", IANA: "Continent/City", Latitude: 32.1, Longitude: 34.85}
 
 	measurementsToCheck := []float32{2.4, 11.3}
 	for i, measurementToCheck := range measurementsToCheck {
@@ -165,7 +165,7 @@ func TestMeasureAndReportFunction_IndexSeverityChanged(t *testing.T) {
 func TestMeasureAndReportFunction_IndexSeverityMaintained(t *testing.T) {
 	provider := &testMeasurementProvider{MeasurementForLocation: make(map[string]float32)}
 	reporter := &testMeasurementReporter{ReportedLocations: make(map[string]float32)}
-	location := &uv.Location{DisplayName: "test", IANA: "Continent/City", Latitude: "222.222", Longitude: "333.333"}
+	location := &uv.Location{DisplayName: "test", IANA: "Continent/City", Latitude: 32.1, Longitude: 34.85}
 
 	measurementsToCheck := []float32{3.1, 4.2}
 	for i, measurementToCheck := range measurementsToCheck {
@@ -188,7 +188,7 @@ func TestMeasureAndReportFunction_IndexSeverityMaintained(t *testing.T) {
 
 func TestMeasureAndReportFunction_FailOnMeasure(t *testing.T) {
 	provider := &testMeasurementProvider{FailOnLocation: map[string]bool{"test": true}}
-	location := &uv.Location{DisplayName: "test", IANA: "Continent/City", Latitude: "222.222", Longitude: "333.333"}
+	location := &uv.Location{DisplayName: "test", IANA: "Continent/City", Latitude: 32.1, Longitude: 34.85}
 
 	err := uv.GetMeasureAndReportFunction(provider, nil)(location)
 	if err == nil {
@@ -199,7 +199,7 @@ func TestMeasureAndReportFunction_FailOnMeasure(t *testing.T) {
 func TestMeasureAndReportFunction_FailOnReport(t *testing.T) {
 	provider := &testMeasurementProvider{MeasurementForLocation: map[string]float32{"test": 11.3}}
 	reporter := &testMeasurementReporter{FailOnLocation: map[string]bool{"test": true}}
-	location := &uv.Location{DisplayName: "test", IANA: "Continent/City", Latitude: "222.222", Longitude: "333.333"}
+	location := &uv.Location{DisplayName: "test", IANA: "Continent/City", Latitude: 32.1, Longitude: 34.85}
 
 	err := uv.GetMeasureAndReportFunction(provider, reporter)(location)
 	if err == nil {
@@ -237,11 +237,11 @@ func TestOpenWeatherMap_Measure(t *testing.T) {
 			t.Errorf("Expected URL path %s but got %s", "/data/2.5/onecall", r.URL.Path)
 		}
 		query := r.URL.Query()
-		if query.Get("lat") != uv.Locations[0].Latitude {
-			t.Errorf("Expected lat query param %s but got %s", uv.Locations[0].Latitude, query.Get("lat"))
+		if query.Get("lat") != "32.109333" {
+			t.Errorf("Expected lat query param %s but got %s", "32.109333", query.Get("lat"))
 		}
-		if query.Get("lon") != uv.Locations[0].Longitude {
-			t.Errorf("Expected lon query param %s but got %s", uv.Locations[0].Longitude, query.Get("lon"))
+		if query.Get("lon") != "34.855499" {
+			t.Errorf("Expected lon query param %s but got %s", "34.855499", query.Get("lon"))
 		}
 		if query.Get("appid") != "abcd" {
 			t.Errorf("Expected appid query param %s but got %s", "abcd", query.Get("appid"))
@@ -250,16 +250,55 @@ func TestOpenWeatherMap_Measure(t *testing.T) {
 			t.Errorf("Expected exclude query param %s but got %s", "minutely,hourly,alerts,daily", query.Get("exclude"))
 		}
 		encoder := json.NewEncoder(w)
-		encoder.Encode(&uv.OneCallResponse{Current: &uv.OneCallCurrent{UVI: 5.32}})
+		encoder.Encode(&uv.OneCallResponse{Current: &uv.OneCallCurrent{DT: 1624266000, Sunrise: 1624241000, Sunset: 1624293000, Clouds: 20, UVI: 5.32}})
 	}))
 	defer server.Close()
 	openWeatherMap := &uv.OpenWeatherMap{Host: server.URL, AppID: "abcd"}
-	uvIndex, measurementError := openWeatherMap.Measure(uv.Locations[0])
+	measurement, measurementError := openWeatherMap.Measure(uv.Locations[0])
 	if measurementError != nil {
 		t.Error(fmt.Errorf("Unexpected error: %w", measurementError))
 	}
-	if uvIndex != 5.32 {
-		t.Errorf("Expected index %f but got %f", 5.32, uvIndex)
+	if measurement.UVIndex != 5.32 {
+		t.Errorf("Expected index %f but got %f", 5.32, measurement.UVIndex)
+	}
+	if measurement.Source != "openweathermap" {
+		t.Errorf("Expected source %s but got %s", "openweathermap", measurement.Source)
+	}
+	if measurement.ObservedAt.Unix() != 1624266000 {
+		t.Errorf("Expected observation time %d but got %d", 1624266000, measurement.ObservedAt.Unix())
+	}
+	if measurement.Sunrise.Unix() != 1624241000 || measurement.Sunset.Unix() != 1624293000 {
+		t.Errorf("Unexpected sunrise %s and sunset %s", measurement.Sunrise, measurement.Sunset)
+	}
+	if *measurement.CloudCover != 20 {
+		t.Errorf("Expected cloud cover %.1f but got %.1f", 20.0, *measurement.CloudCover)
+	}
+}
+
+func TestOpenWeatherMap_MeasureMissingTimes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"current": {"uvi": 5.32}}`))
+	}))
+	defer server.Close()
+	openWeatherMap := &uv.OpenWeatherMap{Host: server.URL, AppID: "abcd"}
+	measurement, measurementError := openWeatherMap.Measure(uv.Locations[0])
+	if measurementError != nil {
+		t.Fatal(measurementError)
+	}
+	if !measurement.ObservedAt.IsZero() || !measurement.Sunrise.IsZero() || !measurement.Sunset.IsZero() {
+		t.Errorf("Expected missing times to be zero but got %s, %s and %s", measurement.ObservedAt, measurement.Sunrise, measurement.Sunset)
+	}
+}
+
+func TestOpenWeatherMap_FailOnMissingCurrent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+	openWeatherMap := &uv.OpenWeatherMap{Host: server.URL, AppID: "abcd"}
+	_, measurementError := openWeatherMap.Measure(uv.Locations[0])
+	if measurementError == nil {
+		t.Error("Expected an error")
 	}
 }
 
@@ -292,9 +331,9 @@ func TestSTDOutMeasurementReporter(t *testing.T) {
 	os.Stdout = w
 
 	stdoutReporter := &uv.STDOutMeasurementReporter{}
-	stdoutReporter.Report(uv.Locations[0], 1.0)
-	stdoutReporter.Report(uv.Locations[0], 4.0)
-	stdoutReporter.Report(uv.Locations[0], 11.0)
+	stdoutReporter.Report(uv.Locations[0], &uv.Measurement{UVIndex: 1.0})
+	stdoutReporter.Report(uv.Locations[0], &uv.Measurement{UVIndex: 4.0})
+	stdoutReporter.Report(uv.Locations[0], &uv.Measurement{UVIndex: 11.0})
 
 	w.Close()
 	os.Stdout = origStdout
@@ -318,3 +357,135 @@ func TestSTDOutMeasurementReporter(t *testing.T) {
 		t.Errorf("Expected %s to contain %s", got, expectedHigh)
 	}
 }
+
+type testMeasurementRecorder struct {
+	FailOnLocation map[string]bool
+	Recorded       []*uv.Measurement
+}
+
+func (t *testMeasurementRecorder) Record(location *uv.Location, measurement *uv.Measurement) error {
+	if t.FailOnLocation[location.DisplayName] {
+		return errors.New("something happened")
+	}
+	t.Recorded = append(t.Recorded, measurement)
+	return nil
+}
+
+func TestMeasureAndReportFunction_RecordsEveryMeasurement(t *testing.T) {
+	provider := &testMeasurementProvider{MeasurementForLocation: make(map[string]float32)}
+	reporter := &testMeasurementReporter{ReportedLocations: make(map[string]float32)}
+	recorder := &testMeasurementRecorder{}
+	failingRecorder := &testMeasurementRecorder{FailOnLocation: map[string]bool{"record-test": true}}
+	location := &uv.Location{DisplayName: "record-test", IANA: "Continent/City", Latitude: 32.1, Longitude: 34.85}
+
+	for _, measurementToCheck := range []float32{3.1, 4.2} {
+		provider.MeasurementForLocation["record-test"] = measurementToCheck
+		err := uv.GetMeasureAndReportFunction(provider, reporter, failingRecorder, recorder)(location)
+		if err != nil {
+			t.Error(fmt.Errorf("Unexpected error: %w", err))
+		}
+	}
+
+	if len(recorder.Recorded) != 2 {
+		t.Fatalf("Expected %d recorded measurements but got %d", 2, len(recorder.Recorded))
+	}
+	if recorder.Recorded[1].UVIndex != 4.2 {
+		t.Errorf("Expected recorded UV index %.1f but got %.1f", 4.2, recorder.Recorded[1].UVIndex)
+	}
+	if recorder.Recorded[0].ObservedAt.IsZero() {
+		t.Error("Expected a missing observation time to be filled in")
+	}
+	if uv.LatestMeasurement(location).UVIndex != 4.2 {
+		t.Errorf("Expected latest UV index %.1f but got %.1f", 4.2, uv.LatestMeasurement(location).UVIndex)
+	}
+}
+
+func TestDaylightAwareFunction(t *testing.T) {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	location := &uv.Location{DisplayName: "daylight-test", IANA: "Asia/Jerusalem", Latitude: uv.TelAviv.Latitude, Longitude: uv.TelAviv.Longitude}
+	measured := 0
+	measurerReporter := func(location *uv.Location) error {
+		measured++
+		return nil
+	}
+	now := time.Date(2021, time.June, 21, 19, 0, 0, 0, jerusalem)
+	daylightAware := uv.GetDaylightAwareFunction(measurerReporter, &uv.DaylightSettings{MinSolarElevation: uv.DefaultNightSolarElevation, Now: func() time.Time { return now }})
+
+	// The sun sets at about 19:50 and rises at about 05:33
+	for _, clock := range []time.Duration{0, 30 * time.Minute, 40 * time.Minute, 2 * time.Hour, 8 * time.Hour, 11 * time.Hour, 11*time.Hour + 30*time.Minute} {
+		now = time.Date(2021, time.June, 21, 19, 0, 0, 0, jerusalem).Add(clock)
+		if err := daylightAware(location); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if measured != 4 {
+		t.Errorf("Expected %d measurements around daylight but got %d", 4, measured)
+	}
+	if latest := uv.LatestMeasurement(location); latest != nil {
+		t.Errorf("Expected the night not to be stored as the latest measurement but got %+v", latest)
+	}
+}
+
+func TestDaylightAwareFunction_InvalidCoordinates(t *testing.T) {
+	location := &uv.Location{DisplayName: "daylight-test", IANA: "Asia/Jerusalem", Latitude: 222.222, Longitude: 34.85}
+	daylightAware := uv.GetDaylightAwareFunction(func(location *uv.Location) error { return nil }, &uv.DaylightSettings{})
+	if err := daylightAware(location); err == nil {
+		t.Error("Expected an error for invalid coordinates")
+	}
+}
+
+func TestAdaptivePollingNextInterval(t *testing.T) {
+	adaptive := &uv.AdaptivePolling{MinInterval: time.Minute, MaxInterval: 15 * time.Minute}
+	observedAt := time.Date(2021, 6, 21, 9, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		previous *uv.Measurement
+		latest   *uv.Measurement
+		expected time.Duration
+	}{
+		{"near an alert threshold", nil, &uv.Measurement{UVIndex: 7.7, ObservedAt: observedAt}, time.Minute},
+		{"near a category boundary without an alert", nil, &uv.Measurement{UVIndex: 5.9, ObservedAt: observedAt}, 15 * time.Minute},
+		{"no previous measurement", nil, &uv.Measurement{UVIndex: 4.5, ObservedAt: observedAt}, 15 * time.Minute},
+		{"stable", &uv.Measurement{UVIndex: 4.5, ObservedAt: observedAt.Add(-10 * time.Minute)}, &uv.Measurement{UVIndex: 4.5, ObservedAt: observedAt}, 15 * time.Minute},
+		{"changing quickly", &uv.Measurement{UVIndex: 4, ObservedAt: observedAt.Add(-10 * time.Minute)}, &uv.Measurement{UVIndex: 4.5, ObservedAt: observedAt}, 15 * time.Minute},
+		{"changing very quickly", &uv.Measurement{UVIndex: 3.5, ObservedAt: observedAt.Add(-5 * time.Minute)}, &uv.Measurement{UVIndex: 4.5, ObservedAt: observedAt}, 3*time.Minute + 45*time.Second},
+		{"far above the highest boundary", nil, &uv.Measurement{UVIndex: 14, ObservedAt: observedAt}, 15 * time.Minute},
+	}
+	for _, testCase := range cases {
+		if interval := adaptive.NextInterval(testCase.previous, testCase.latest); interval != testCase.expected {
+			t.Errorf("%s: expected an interval of %s but got %s", testCase.name, testCase.expected, interval)
+		}
+	}
+}
+
+func TestMeasureAndReport_AdaptivePolling(t *testing.T) {
+	measuredLocations := map[string]int{}
+	measurerReporter := func(location *uv.Location) error {
+		measuredLocations[location.DisplayName]++
+		return errors.New("something happened")
+	}
+
+	exitChan := make(chan bool)
+	measureAndReportExitChan := make(chan bool)
+	settings := &uv.MeasurementSettings{
+		LoopInterval:    50 * time.Millisecond,
+		PollInterval:    time.Hour,
+		ExitChan:        exitChan,
+		AdaptivePolling: &uv.AdaptivePolling{MinInterval: time.Millisecond, MaxInterval: time.Millisecond},
+	}
+	go func() {
+		uv.MeasureAndReport(measurerReporter, settings)
+		measureAndReportExitChan <- true
+	}()
+
+	time.Sleep(300 * time.Millisecond)
+
+	exitChan <- true
+	<-measureAndReportExitChan
+
+	for _, location := range uv.Locations {
+		if measuredLocations[location.DisplayName] != 1 {
+			t.Errorf("Expected %s to be measured once and then wait for the poll interval after the failure, but it was measured %d times", location.DisplayName, measuredLocations[location.DisplayName])
+		}
+	}
+}
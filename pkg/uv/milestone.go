@@ -0,0 +1,288 @@
+package uv
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// MilestoneKind is the kind of record that a Milestone marks.
+type MilestoneKind int
+
+const (
+	// MilestoneYearlyHigh is the highest UV index of the year so far
+	MilestoneYearlyHigh MilestoneKind = iota
+	// MilestoneEarliestCategory is the earliest date in the year on which a
+	// category was reached, compared to previous years
+	MilestoneEarliestCategory
+	// MilestoneClimatologyAnomaly is a UV index well above the average daily
+	// peak of the month in previous years
+	MilestoneClimatologyAnomaly
+)
+
+func (kind MilestoneKind) String() string {
+	switch kind {
+	case MilestoneYearlyHigh:
+		return "yearly high"
+	case MilestoneEarliestCategory:
+		return "earliest category"
+	case MilestoneClimatologyAnomaly:
+		return "climatology anomaly"
+	}
+	return fmt.Sprintf("MilestoneKind(%d)", int(kind))
+}
+
+// Milestone is a record set by a measurement. At and PreviousEarliest are in
+// the location's time zone.
+type Milestone struct {
+	Location *Location
+	Kind     MilestoneKind
+	At       time.Time
+	UVIndex  float32
+	// RawUVIndex is the provider's value when UVIndex was corrected
+	RawUVIndex *float32
+	Category   Category
+	// PreviousHigh is the highest UV index of the year before At, for a
+	// MilestoneYearlyHigh
+	PreviousHigh float32
+	// PreviousEarliest is when Category was first reached in the year of the
+	// previous record, for a MilestoneEarliestCategory
+	PreviousEarliest time.Time
+	// Climatology is set for a MilestoneClimatologyAnomaly
+	Climatology *Climatology
+}
+
+// Anomaly is how far the UV index is above the climatological average.
+func (milestone *Milestone) Anomaly() float32 {
+	if milestone.Climatology == nil {
+		return 0
+	}
+	return milestone.UVIndex - milestone.Climatology.AverageDailyPeak
+}
+
+// DefaultMilestoneRateLimits is the minimum time between two milestone posts
+// of the same kind and location.
+var DefaultMilestoneRateLimits = map[MilestoneKind]time.Duration{
+	MilestoneYearlyHigh:         24 * time.Hour,
+	MilestoneEarliestCategory:   24 * time.Hour,
+	MilestoneClimatologyAnomaly: 72 * time.Hour,
+}
+
+const (
+	DefaultClimatologyYears        = 10
+	DefaultAnomalyThreshold        = float32(2)
+	DefaultMinimumClimatologyDays  = 20
+	yearlyHighMinimumHistoryLength = 30 * 24 * time.Hour
+)
+
+// MilestoneTask compares the latest measurement of every location with its
+// History and posts the milestones it sets. A yearly high needs 30 days of
+// history in the same year, and is only posted from the protection threshold
+// up. Seasons are calendar years.
+type MilestoneTask struct {
+	Locations []*Location
+	History   *History
+	Reporters []MessageReporter
+	// SeasonCategory is the category whose earliest date is tracked,
+	// CategoryExtreme when zero
+	SeasonCategory Category
+	// ClimatologyYears is how many past years the climatology and the earliest
+	// dates are taken from, DefaultClimatologyYears when zero
+	ClimatologyYears int
+	// AnomalyThreshold is how far above the climatology the UV index must be,
+	// DefaultAnomalyThreshold when zero
+	AnomalyThreshold float32
+	// MinimumClimatologyDays is how many days of the month the climatology
+	// needs, DefaultMinimumClimatologyDays when zero
+	MinimumClimatologyDays int
+	// RateLimits replaces DefaultMilestoneRateLimits when set
+	RateLimits map[MilestoneKind]time.Duration
+
+	evaluatedForLocation   map[string]time.Time
+	postedForLocation      map[string]map[MilestoneKind]time.Time
+	climatologyForLocation map[string]*dailyClimatology
+}
+
+type dailyClimatology struct {
+	date        string
+	climatology *Climatology
+}
+
+func (task *MilestoneTask) Run(now time.Time) error {
+	for _, location := range task.Locations {
+		if milestoneError := task.post(location, now); milestoneError != nil {
+			log.Println(fmt.Errorf("failed to post the milestones of %s: %w", location.DisplayName, milestoneError))
+		}
+	}
+	return nil
+}
+
+func (task *MilestoneTask) post(location *Location, now time.Time) error {
+	measurement := LatestMeasurement(location)
+	if measurement == nil || task.evaluatedForLocation[location.DisplayName].Equal(measurement.ObservedAt) {
+		return nil
+	}
+	if task.evaluatedForLocation == nil {
+		task.evaluatedForLocation = map[string]time.Time{}
+	}
+	task.evaluatedForLocation[location.DisplayName] = measurement.ObservedAt
+
+	milestones, milestonesError := task.Milestones(location, measurement)
+	if milestonesError != nil {
+		return milestonesError
+	}
+	if len(milestones) == 0 {
+		return nil
+	}
+	templates, templatesError := getTemplates(location)
+	if templatesError != nil {
+		return templatesError
+	}
+	for _, milestone := range milestones {
+		if !task.allowed(location, milestone.Kind, now) {
+			continue
+		}
+		message, renderError := RenderTemplate(milestone.Kind.String()+" milestone", templates.milestone(milestone.Kind), milestone)
+		if renderError != nil {
+			return renderError
+		}
+		if reportError := reportMessage(task.Reporters, location, message); reportError != nil {
+			return reportError
+		}
+		if task.postedForLocation == nil {
+			task.postedForLocation = map[string]map[MilestoneKind]time.Time{}
+		}
+		if task.postedForLocation[location.DisplayName] == nil {
+			task.postedForLocation[location.DisplayName] = map[MilestoneKind]time.Time{}
+		}
+		task.postedForLocation[location.DisplayName][milestone.Kind] = now
+	}
+	return nil
+}
+
+func (task *MilestoneTask) allowed(location *Location, kind MilestoneKind, now time.Time) bool {
+	rateLimits := task.RateLimits
+	if rateLimits == nil {
+		rateLimits = DefaultMilestoneRateLimits
+	}
+	lastPosted, found := task.postedForLocation[location.DisplayName][kind]
+	return !found || now.Sub(lastPosted) >= rateLimits[kind]
+}
+
+// Milestones returns the milestones that the measurement sets compared to the
+// history recorded before it.
+func (task *MilestoneTask) Milestones(location *Location, measurement *Measurement) ([]*Milestone, error) {
+	timeZone, timeZoneError := GetLocation(location.IANA)
+	if timeZoneError != nil {
+		return nil, timeZoneError
+	}
+	localObservedAt := measurement.ObservedAt.In(timeZone)
+	yearStart := time.Date(localObservedAt.Year(), time.January, 1, 0, 0, 0, 0, timeZone)
+	thisYear, rangeError := task.History.Range(location, yearStart, measurement.ObservedAt)
+	if rangeError != nil {
+		return nil, rangeError
+	}
+	var yearlyHigh float32
+	for _, record := range thisYear {
+		if record.UVIndex > yearlyHigh {
+			yearlyHigh = record.UVIndex
+		}
+	}
+
+	milestones := []*Milestone{}
+	newMilestone := func(kind MilestoneKind) *Milestone {
+		return &Milestone{Location: location, Kind: kind, At: localObservedAt, UVIndex: measurement.UVIndex, RawUVIndex: measurement.RawUVIndex, Category: CategoryOf(measurement.UVIndex)}
+	}
+
+	if len(thisYear) > 0 && measurement.ObservedAt.Sub(thisYear[0].ObservedAt) >= yearlyHighMinimumHistoryLength &&
+		measurement.UVIndex >= ProtectionThreshold && measurement.UVIndex > yearlyHigh {
+		milestone := newMilestone(MilestoneYearlyHigh)
+		milestone.PreviousHigh = yearlyHigh
+		milestones = append(milestones, milestone)
+	}
+
+	seasonCategory := task.SeasonCategory
+	if seasonCategory == CategoryLow {
+		seasonCategory = CategoryExtreme
+	}
+	if measurement.UVIndex >= seasonCategory.Threshold() && yearlyHigh < seasonCategory.Threshold() {
+		previousEarliest, earliestError := task.earliestReached(location, seasonCategory, localObservedAt)
+		if earliestError != nil {
+			return nil, earliestError
+		}
+		if !previousEarliest.IsZero() && localObservedAt.Format("01-02") < previousEarliest.Format("01-02") {
+			milestone := newMilestone(MilestoneEarliestCategory)
+			milestone.Category = seasonCategory
+			milestone.PreviousEarliest = previousEarliest
+			milestones = append(milestones, milestone)
+		}
+	}
+
+	climatology, climatologyError := task.climatology(location, localObservedAt)
+	if climatologyError != nil {
+		return nil, climatologyError
+	}
+	minimumDays := task.MinimumClimatologyDays
+	if minimumDays == 0 {
+		minimumDays = DefaultMinimumClimatologyDays
+	}
+	anomalyThreshold := task.AnomalyThreshold
+	if anomalyThreshold == 0 {
+		anomalyThreshold = DefaultAnomalyThreshold
+	}
+	if climatology.Days >= minimumDays && measurement.UVIndex-climatology.AverageDailyPeak >= anomalyThreshold {
+		milestone := newMilestone(MilestoneClimatologyAnomaly)
+		milestone.Climatology = climatology
+		milestones = append(milestones, milestone)
+	}
+	return milestones, nil
+}
+
+func (task *MilestoneTask) climatologyYears() int {
+	if task.ClimatologyYears == 0 {
+		return DefaultClimatologyYears
+	}
+	return task.ClimatologyYears
+}
+
+// earliestReached returns the earliest date in the year on which the category
+// was first reached in any of the previous years, or zero if it never was.
+func (task *MilestoneTask) earliestReached(location *Location, category Category, localNow time.Time) (time.Time, error) {
+	var earliest time.Time
+	for year := localNow.Year() - task.climatologyYears(); year < localNow.Year(); year++ {
+		yearStart := time.Date(year, time.January, 1, 0, 0, 0, 0, localNow.Location())
+		records, rangeError := task.History.Range(location, yearStart, yearStart.AddDate(1, 0, 0))
+		if rangeError != nil {
+			return time.Time{}, rangeError
+		}
+		for _, record := range records {
+			if record.UVIndex < category.Threshold() {
+				continue
+			}
+			reached := record.ObservedAt.In(localNow.Location())
+			if earliest.IsZero() || reached.Format("01-02") < earliest.Format("01-02") {
+				earliest = reached
+			}
+			break
+		}
+	}
+	return earliest, nil
+}
+
+// climatology caches the climatology of the local month for a day, since past
+// years only change when history is backfilled or imported.
+func (task *MilestoneTask) climatology(location *Location, localNow time.Time) (*Climatology, error) {
+	date := localNow.Format("2006-01-02")
+	if cached, found := task.climatologyForLocation[location.DisplayName]; found && cached.date == date {
+		return cached.climatology, nil
+	}
+	climatology, climatologyError := task.History.MonthlyClimatology(location, localNow.Month(), localNow, task.climatologyYears())
+	if climatologyError != nil {
+		return nil, climatologyError
+	}
+	if task.climatologyForLocation == nil {
+		task.climatologyForLocation = map[string]*dailyClimatology{}
+	}
+	task.climatologyForLocation[location.DisplayName] = &dailyClimatology{date: date, climatology: climatology}
+	return climatology, nil
+}
@@ -0,0 +1,179 @@
+package uv_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func TestMilestoneTask_YearlyHigh(t *testing.T) {
+	history := openTestHistory(t)
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	recordHistory(t, history, uv.TelAviv, time.Date(2021, time.January, 1, 12, 0, 0, 0, jerusalem), 30*24*time.Hour, 2, 3.5, 5, 6.2, 7, 6.8)
+	task := &uv.MilestoneTask{Locations: []*uv.Location{uv.TelAviv}, History: history}
+
+	measurement := &uv.Measurement{ObservedAt: time.Date(2021, time.June, 21, 12, 0, 0, 0, jerusalem), UVIndex: 8.1}
+	milestones, err := task.Milestones(uv.TelAviv, measurement)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(milestones) != 1 || milestones[0].Kind != uv.MilestoneYearlyHigh {
+		t.Fatalf("Expected a yearly high but got %+v", milestones)
+	}
+	if milestones[0].PreviousHigh != 7 || milestones[0].Category != uv.CategoryVeryHigh {
+		t.Errorf("Unexpected milestone %+v", milestones[0])
+	}
+
+	measurement.UVIndex = 6.9
+	if milestones, _ = task.Milestones(uv.TelAviv, measurement); len(milestones) != 0 {
+		t.Errorf("Expected no milestones below the yearly high but got %+v", milestones)
+	}
+}
+
+func TestMilestoneTask_YearlyHighNeedsHistory(t *testing.T) {
+	history := openTestHistory(t)
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	recordHistory(t, history, uv.TelAviv, time.Date(2021, time.June, 1, 12, 0, 0, 0, jerusalem), 24*time.Hour, 5, 6)
+	task := &uv.MilestoneTask{Locations: []*uv.Location{uv.TelAviv}, History: history}
+
+	measurement := &uv.Measurement{ObservedAt: time.Date(2021, time.June, 21, 12, 0, 0, 0, jerusalem), UVIndex: 9}
+	if milestones, _ := task.Milestones(uv.TelAviv, measurement); len(milestones) != 0 {
+		t.Errorf("Expected no milestones without 30 days of history but got %+v", milestones)
+	}
+}
+
+func TestMilestoneTask_EarliestCategory(t *testing.T) {
+	history := openTestHistory(t)
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	recordHistory(t, history, uv.TelAviv, time.Date(2019, time.June, 8, 12, 0, 0, 0, jerusalem), 24*time.Hour, 10.5, 10.9, 11.2, 11.5)
+	recordHistory(t, history, uv.TelAviv, time.Date(2020, time.May, 30, 12, 0, 0, 0, jerusalem), 24*time.Hour, 11.1, 10.2)
+	recordHistory(t, history, uv.TelAviv, time.Date(2021, time.May, 1, 12, 0, 0, 0, jerusalem), 24*time.Hour, 9, 10)
+	task := &uv.MilestoneTask{Locations: []*uv.Location{uv.TelAviv}, History: history}
+
+	measurement := &uv.Measurement{ObservedAt: time.Date(2021, time.May, 20, 12, 0, 0, 0, jerusalem), UVIndex: 11.3}
+	milestones, err := task.Milestones(uv.TelAviv, measurement)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(milestones) != 1 || milestones[0].Kind != uv.MilestoneEarliestCategory {
+		t.Fatalf("Expected an earliest category milestone but got %+v", milestones)
+	}
+	if milestones[0].Category != uv.CategoryExtreme || milestones[0].PreviousEarliest.Format("2006-01-02") != "2020-05-30" {
+		t.Errorf("Unexpected milestone %+v", milestones[0])
+	}
+
+	measurement.ObservedAt = time.Date(2021, time.June, 2, 12, 0, 0, 0, jerusalem)
+	milestones, _ = task.Milestones(uv.TelAviv, measurement)
+	for _, milestone := range milestones {
+		if milestone.Kind == uv.MilestoneEarliestCategory {
+			t.Errorf("Expected no earliest category milestone after the earliest date but got %+v", milestone)
+		}
+	}
+}
+
+func TestMilestoneTask_ClimatologyAnomaly(t *testing.T) {
+	// A location of its own keeps the latest measurement from leaking into
+	// other tests
+	location := &uv.Location{DisplayName: "milestone-test", IANA: "Asia/Jerusalem"}
+	uv.TemplatesByLocation[location.DisplayName] = uv.TelAvivTemplates
+	defer delete(uv.TemplatesByLocation, location.DisplayName)
+	history := openTestHistory(t)
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	now := time.Now().In(jerusalem)
+	for year := now.Year() - 2; year < now.Year(); year++ {
+		recordHistory(t, history, location, time.Date(year, now.Month(), 1, 12, 0, 0, 0, jerusalem), 24*time.Hour, 5, 5.5, 6, 6.5, 7)
+	}
+	provider := &testMeasurementProvider{MeasurementForLocation: map[string]float32{location.DisplayName: 9.3}}
+	measureAndReport := uv.GetMeasureAndReportFunction(provider, &testMeasurementReporter{ReportedLocations: map[string]float32{}})
+	reporter := &testMessageReporter{}
+	task := &uv.MilestoneTask{
+		Locations:              []*uv.Location{location},
+		History:                history,
+		Reporters:              []uv.MessageReporter{reporter},
+		ClimatologyYears:       2,
+		MinimumClimatologyDays: 10,
+	}
+
+	measureAndReport(location)
+	task.Run(now)
+	if len(reporter.Messages) != 1 {
+		t.Fatalf("Expected a milestone post but got %d", len(reporter.Messages))
+	}
+	expected := "UV in Tel-Aviv is 9.3, 3.3 points above the 2-year " + now.Month().String() + " average of 6.0"
+	if !strings.HasPrefix(reporter.Messages[0], expected) {
+		t.Errorf("Expected %s to start with %s", reporter.Messages[0], expected)
+	}
+
+	task.Run(now.Add(time.Minute))
+	if len(reporter.Messages) != 1 {
+		t.Error("Expected the same measurement to be evaluated once")
+	}
+
+	time.Sleep(time.Millisecond)
+	measureAndReport(location)
+	task.Run(now.Add(time.Hour))
+	if len(reporter.Messages) != 1 {
+		t.Error("Expected the milestone post to be rate limited")
+	}
+
+	measureAndReport(location)
+	task.Run(now.Add(73 * time.Hour))
+	if len(reporter.Messages) != 2 {
+		t.Errorf("Expected a milestone post after the rate limit but got %d", len(reporter.Messages))
+	}
+}
+
+func TestHistory_MonthlyClimatology(t *testing.T) {
+	history := openTestHistory(t)
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	recordHistory(t, history, uv.TelAviv, time.Date(2019, time.October, 1, 12, 0, 0, 0, jerusalem), 24*time.Hour, 4, 6)
+	recordHistory(t, history, uv.TelAviv, time.Date(2020, time.October, 1, 12, 0, 0, 0, jerusalem), 24*time.Hour, 5)
+	recordHistory(t, history, uv.TelAviv, time.Date(2020, time.November, 1, 12, 0, 0, 0, jerusalem), 24*time.Hour, 2)
+	recordHistory(t, history, uv.TelAviv, time.Date(2021, time.October, 1, 12, 0, 0, 0, jerusalem), 24*time.Hour, 9)
+
+	climatology, err := history.MonthlyClimatology(uv.TelAviv, time.October, time.Date(2021, time.October, 20, 0, 0, 0, 0, jerusalem), 10)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if climatology.Years != 2 || climatology.Days != 3 || climatology.AverageDailyPeak != 5 {
+		t.Errorf("Unexpected climatology %+v", climatology)
+	}
+}
+
+func TestMilestoneTask_RetriesFailedPost(t *testing.T) {
+	location := &uv.Location{DisplayName: "milestone-failure-test", IANA: "Asia/Jerusalem"}
+	uv.TemplatesByLocation[location.DisplayName] = uv.TelAvivTemplates
+	defer delete(uv.TemplatesByLocation, location.DisplayName)
+	history := openTestHistory(t)
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	now := time.Now().In(jerusalem)
+	for year := now.Year() - 2; year < now.Year(); year++ {
+		recordHistory(t, history, location, time.Date(year, now.Month(), 1, 12, 0, 0, 0, jerusalem), 24*time.Hour, 5, 5.5, 6, 6.5, 7)
+	}
+	provider := &testMeasurementProvider{MeasurementForLocation: map[string]float32{location.DisplayName: 9.3}}
+	measureAndReport := uv.GetMeasureAndReportFunction(provider, &testMeasurementReporter{ReportedLocations: map[string]float32{}})
+	reporter := &testMessageReporter{FailOnLocation: map[string]bool{location.DisplayName: true}}
+	task := &uv.MilestoneTask{
+		Locations:              []*uv.Location{location},
+		History:                history,
+		Reporters:              []uv.MessageReporter{reporter},
+		ClimatologyYears:       2,
+		MinimumClimatologyDays: 10,
+	}
+
+	measureAndReport(location)
+	task.Run(now)
+	if len(reporter.Messages) != 0 {
+		t.Fatalf("Expected the milestone post to fail but got %v", reporter.Messages)
+	}
+
+	reporter.FailOnLocation = nil
+	time.Sleep(time.Millisecond)
+	measureAndReport(location)
+	task.Run(now.Add(time.Hour))
+	if len(reporter.Messages) != 1 {
+		t.Errorf("Expected a failed milestone post not to be rate limited but got %d posts", len(reporter.Messages))
+	}
+}
@@ -0,0 +1,99 @@
+package uv
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// DefaultNearThreshold is how close in UV index to an alert threshold a
+// measurement must be to be polled at the minimum interval.
+const DefaultNearThreshold float32 = 0.5
+
+// AdaptivePolling polls each location at the minimum interval when its UV index
+// is close to the threshold of an AlertLevel, and otherwise at half of the time
+// that the current rate of change would take to reach the nearest threshold,
+// within the minimum and maximum intervals. Only the alert thresholds count,
+// since crossing any other boundary posts nothing.
+type AdaptivePolling struct {
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	// NearThreshold is DefaultNearThreshold when zero
+	NearThreshold float32
+}
+
+// NextInterval returns the time to wait after the latest measurement. The
+// previous measurement, which may be nil, gives the rate of change.
+func (adaptive *AdaptivePolling) NextInterval(previous *Measurement, latest *Measurement) time.Duration {
+	nearThreshold := adaptive.NearThreshold
+	if nearThreshold == 0 {
+		nearThreshold = DefaultNearThreshold
+	}
+	distance := distanceToAlertThreshold(latest.UVIndex)
+	if distance <= nearThreshold {
+		return adaptive.MinInterval
+	}
+	interval := adaptive.MaxInterval
+	if previous != nil && latest.ObservedAt.After(previous.ObservedAt) {
+		ratePerHour := math.Abs(float64(latest.UVIndex-previous.UVIndex)) / latest.ObservedAt.Sub(previous.ObservedAt).Hours()
+		if ratePerHour > 0 {
+			interval = time.Duration(float64(distance) / ratePerHour / 2 * float64(time.Hour))
+		}
+	}
+	if interval < adaptive.MinInterval {
+		return adaptive.MinInterval
+	}
+	if interval > adaptive.MaxInterval {
+		return adaptive.MaxInterval
+	}
+	return interval
+}
+
+// distanceToAlertThreshold is how far the UV index is from the nearest
+// threshold that IndexHasChanged alerts on, in either direction.
+func distanceToAlertThreshold(uvIndex float32) float32 {
+	distance := float32(math.MaxFloat32)
+	for _, level := range AlertLevels[1:] {
+		if levelDistance := float32(math.Abs(float64(uvIndex - level.Threshold()))); levelDistance < distance {
+			distance = levelDistance
+		}
+	}
+	return distance
+}
+
+// adaptivePoller keeps the polling schedule of every location.
+type adaptivePoller struct {
+	settings            *AdaptivePolling
+	fallbackInterval    time.Duration
+	nextPollForLocation map[string]time.Time
+	previousForLocation map[string]*Measurement
+}
+
+func newAdaptivePoller(settings *AdaptivePolling, fallbackInterval time.Duration) *adaptivePoller {
+	return &adaptivePoller{
+		settings:            settings,
+		fallbackInterval:    fallbackInterval,
+		nextPollForLocation: map[string]time.Time{},
+		previousForLocation: map[string]*Measurement{},
+	}
+}
+
+// poll measures the locations that are due. A location that failed to be
+// measured is retried after the fallback interval.
+func (poller *adaptivePoller) poll(locations []*Location, measurerReporter MeasurerReporter, now time.Time) {
+	for _, location := range locations {
+		if now.Before(poller.nextPollForLocation[location.DisplayName]) {
+			continue
+		}
+		interval := poller.fallbackInterval
+		if measureError := measurerReporter(location); measureError != nil {
+			log.Println(fmt.Errorf("failed to measurer and report %s: %w", location.DisplayName, measureError))
+		} else if latest := LatestMeasurement(location); latest != nil {
+			interval = poller.settings.NextInterval(poller.previousForLocation[location.DisplayName], latest)
+			poller.previousForLocation[location.DisplayName] = latest
+		}
+		poller.nextPollForLocation[location.DisplayName] = now.Add(interval)
+		log.Printf("Measured %s, next measurement in %s\n", location.DisplayName, interval)
+	}
+}
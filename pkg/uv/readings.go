@@ -0,0 +1,68 @@
+package uv
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrNoReading = errors.New("no reading was received")
+var ErrStaleReading = errors.New("the latest reading is stale")
+
+// StationReading is a UV reading pushed to the bot by a weather station.
+type StationReading struct {
+	Station    string
+	UVIndex    float32
+	ObservedAt time.Time
+	ReceivedAt time.Time
+}
+
+func (reading *StationReading) measurement(source string) *Measurement {
+	return &Measurement{ObservedAt: reading.ObservedAt, Source: source, UVIndex: reading.UVIndex}
+}
+
+type latestReadings struct {
+	mutex      sync.Mutex
+	byLocation map[string]*StationReading
+}
+
+func (readings *latestReadings) update(displayName string, reading *StationReading) {
+	readings.mutex.Lock()
+	defer readings.mutex.Unlock()
+	if readings.byLocation == nil {
+		readings.byLocation = map[string]*StationReading{}
+	}
+	readings.byLocation[displayName] = reading
+}
+
+// updateIfNewer only keeps the reading if it was observed after the one that is
+// already known for the location.
+func (readings *latestReadings) updateIfNewer(displayName string, reading *StationReading) {
+	readings.mutex.Lock()
+	defer readings.mutex.Unlock()
+	if readings.byLocation == nil {
+		readings.byLocation = map[string]*StationReading{}
+	}
+	existing, found := readings.byLocation[displayName]
+	if found && existing.ObservedAt.After(reading.ObservedAt) {
+		return
+	}
+	readings.byLocation[displayName] = reading
+}
+
+// get returns the latest reading for the location, failing if none was received
+// or the station stopped reporting for longer than maxAge.
+func (readings *latestReadings) get(location *Location, maxAge time.Duration, now time.Time) (*StationReading, error) {
+	readings.mutex.Lock()
+	defer readings.mutex.Unlock()
+	reading, found := readings.byLocation[location.DisplayName]
+	if !found {
+		return nil, fmt.Errorf("%s: %w", location.DisplayName, ErrNoReading)
+	}
+	age := now.Sub(reading.ReceivedAt)
+	if maxAge > 0 && age > maxAge {
+		return nil, fmt.Errorf("station %s of %s last reported %s ago: %w", reading.Station, location.DisplayName, age.Round(time.Second), ErrStaleReading)
+	}
+	return reading, nil
+}
@@ -0,0 +1,179 @@
+package uv
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// RecapPeriod is the length of time that a Recap covers.
+type RecapPeriod int
+
+const (
+	RecapWeekly RecapPeriod = iota
+	RecapMonthly
+)
+
+func (period RecapPeriod) String() string {
+	switch period {
+	case RecapWeekly:
+		return "weekly"
+	case RecapMonthly:
+		return "monthly"
+	}
+	return fmt.Sprintf("RecapPeriod(%d)", int(period))
+}
+
+// start returns the start of the period that ends at end.
+func (period RecapPeriod) start(end time.Time) time.Time {
+	if period == RecapMonthly {
+		return end.AddDate(0, -1, 0)
+	}
+	return end.AddDate(0, 0, -7)
+}
+
+// Recap is the observed UV of a week or a month, based on the daily peaks in
+// the history. Start and End are local midnights, and End is exclusive.
+type Recap struct {
+	Location         *Location
+	Period           RecapPeriod
+	Start            time.Time
+	End              time.Time
+	Days             int
+	AverageDailyPeak float32
+	MaxUVIndex       float32
+	MaxTime          time.Time
+	// DaysInCategory counts the days by the category of their peak
+	DaysInCategory map[Category]int
+	// LongestHighStreak is the most consecutive days that peaked at High or
+	// above
+	LongestHighStreak int
+	// Previous is the recap of the period before, or nil when there is no
+	// history of it
+	Previous *Recap
+}
+
+// AveragePeakChange is the difference between the average daily peak and the
+// one of the previous period.
+func (recap *Recap) AveragePeakChange() float32 {
+	if recap.Previous == nil {
+		return 0
+	}
+	return recap.AverageDailyPeak - recap.Previous.AverageDailyPeak
+}
+
+// NewRecap summarizes the period of the location that ends at the local
+// midnight of end, along with the period before it.
+func NewRecap(history *History, location *Location, period RecapPeriod, end time.Time) (*Recap, error) {
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, end.Location())
+	recap, recapError := newRecap(history, location, period, period.start(end), end)
+	if recapError != nil {
+		return nil, recapError
+	}
+	if recap.Days == 0 {
+		return nil, fmt.Errorf("the history of %s has no records from %s until %s", location.DisplayName, recap.Start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+	previous, previousError := newRecap(history, location, period, period.start(recap.Start), recap.Start)
+	if previousError != nil {
+		return nil, previousError
+	}
+	if previous.Days > 0 {
+		recap.Previous = previous
+	}
+	return recap, nil
+}
+
+func newRecap(history *History, location *Location, period RecapPeriod, start time.Time, end time.Time) (*Recap, error) {
+	dailyExtremes, extremesError := history.DailyExtremes(location, start, end)
+	if extremesError != nil {
+		return nil, extremesError
+	}
+	recap := &Recap{Location: location, Period: period, Start: start, End: end, Days: len(dailyExtremes), DaysInCategory: map[Category]int{}}
+	var peakSum float32
+	streak := 0
+	for i, extremes := range dailyExtremes {
+		peakSum += extremes.MaxUVIndex
+		if i == 0 || extremes.MaxUVIndex > recap.MaxUVIndex {
+			recap.MaxUVIndex = extremes.MaxUVIndex
+			recap.MaxTime = extremes.MaxTime
+		}
+		category := CategoryOf(extremes.MaxUVIndex)
+		recap.DaysInCategory[category]++
+		if category < CategoryHigh {
+			streak = 0
+			continue
+		}
+		if i > 0 && streak > 0 && extremes.Date.Format("2006-01-02") == dailyExtremes[i-1].Date.AddDate(0, 0, 1).Format("2006-01-02") {
+			streak++
+		} else {
+			streak = 1
+		}
+		if streak > recap.LongestHighStreak {
+			recap.LongestHighStreak = streak
+		}
+	}
+	if recap.Days > 0 {
+		recap.AverageDailyPeak = peakSum / float32(recap.Days)
+	}
+	return recap, nil
+}
+
+// RecapTask posts a recap of every location at a local time of day. A weekly
+// recap is posted on Weekday and covers the seven days before it, and a monthly
+// recap is posted on the first day of the month and covers the month before.
+type RecapTask struct {
+	Locations []*Location
+	History   *History
+	Reporters []MessageReporter
+	Period    RecapPeriod
+	At        ClockTime
+	Weekday   time.Weekday
+
+	trigger dailyTrigger
+}
+
+func (task *RecapTask) Run(now time.Time) error {
+	for _, location := range task.Locations {
+		if recapError := task.recap(location, now); recapError != nil {
+			log.Println(fmt.Errorf("failed to post the %s recap of %s: %w", task.Period, location.DisplayName, recapError))
+		}
+	}
+	return nil
+}
+
+func (task *RecapTask) recap(location *Location, now time.Time) error {
+	timeZone, timeZoneError := GetLocation(location.IANA)
+	if timeZoneError != nil {
+		return timeZoneError
+	}
+	localNow := now.In(timeZone)
+	if task.Period == RecapWeekly && localNow.Weekday() != task.Weekday {
+		return nil
+	}
+	if task.Period == RecapMonthly && localNow.Day() != 1 {
+		return nil
+	}
+	at := task.At.On(localNow)
+	if !task.trigger.due(location, at, localNow) {
+		return nil
+	}
+	task.trigger.fired(location, at)
+
+	recap, recapError := NewRecap(task.History, location, task.Period, localNow)
+	if recapError != nil {
+		return recapError
+	}
+	templates, templatesError := getTemplates(location)
+	if templatesError != nil {
+		return templatesError
+	}
+	text := templates.WeeklyRecap
+	if task.Period == RecapMonthly {
+		text = templates.MonthlyRecap
+	}
+	message, renderError := RenderTemplate(task.Period.String()+" recap", text, recap)
+	if renderError != nil {
+		return renderError
+	}
+	return reportMessage(task.Reporters, location, message)
+}
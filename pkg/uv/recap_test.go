@@ -0,0 +1,104 @@
+package uv_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func TestNewRecap(t *testing.T) {
+	history := openTestHistory(t)
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	recordHistory(t, history, uv.TelAviv, time.Date(2021, time.June, 13, 12, 0, 0, 0, jerusalem), 24*time.Hour, 4, 5, 5, 6, 7)
+	recordHistory(t, history, uv.TelAviv, time.Date(2021, time.June, 20, 12, 0, 0, 0, jerusalem), 24*time.Hour, 7, 8.5, 5, 9, 9.5, 6.5, 2.5)
+
+	recap, err := uv.NewRecap(history, uv.TelAviv, uv.RecapWeekly, time.Date(2021, time.June, 27, 9, 0, 0, 0, jerusalem))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if recap.Days != 7 || recap.Start.Format("2006-01-02") != "2021-06-20" {
+		t.Errorf("Unexpected recap period %+v", recap)
+	}
+	if recap.AverageDailyPeak != 6.857143 {
+		t.Errorf("Expected an average daily peak of %f but got %f", 6.857143, recap.AverageDailyPeak)
+	}
+	if recap.MaxUVIndex != 9.5 || recap.MaxTime.Weekday() != time.Thursday {
+		t.Errorf("Unexpected maximum %f at %v", recap.MaxUVIndex, recap.MaxTime)
+	}
+	if recap.DaysInCategory[uv.CategoryHigh] != 2 || recap.DaysInCategory[uv.CategoryVeryHigh] != 3 || recap.DaysInCategory[uv.CategoryModerate] != 1 || recap.DaysInCategory[uv.CategoryLow] != 1 {
+		t.Errorf("Unexpected days in category %v", recap.DaysInCategory)
+	}
+	if recap.LongestHighStreak != 3 {
+		t.Errorf("Expected a longest High+ streak of %d but got %d", 3, recap.LongestHighStreak)
+	}
+	if recap.Previous == nil || recap.Previous.Days != 5 || recap.Previous.AverageDailyPeak != 5.4 {
+		t.Fatalf("Unexpected previous recap %+v", recap.Previous)
+	}
+	if recap.AveragePeakChange() != recap.AverageDailyPeak-5.4 {
+		t.Errorf("Unexpected average peak change %f", recap.AveragePeakChange())
+	}
+}
+
+func TestNewRecap_NoHistory(t *testing.T) {
+	history := openTestHistory(t)
+	if _, err := uv.NewRecap(history, uv.TelAviv, uv.RecapMonthly, time.Date(2021, time.July, 1, 9, 0, 0, 0, time.UTC)); err == nil {
+		t.Error("Expected an error without any history")
+	}
+}
+
+func TestRecapTask_Weekly(t *testing.T) {
+	history := openTestHistory(t)
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	recordHistory(t, history, uv.TelAviv, time.Date(2021, time.June, 20, 12, 0, 0, 0, jerusalem), 24*time.Hour, 7, 8.5, 5, 9, 9.5, 6.5, 2.5)
+	reporter := &testMessageReporter{}
+	task := &uv.RecapTask{
+		Locations: []*uv.Location{uv.TelAviv},
+		History:   history,
+		Reporters: []uv.MessageReporter{reporter},
+		Period:    uv.RecapWeekly,
+		At:        uv.ClockTime{Hour: 9},
+		Weekday:   time.Sunday,
+	}
+
+	task.Run(time.Date(2021, time.June, 26, 9, 5, 0, 0, jerusalem))
+	if len(reporter.Messages) != 0 {
+		t.Error("Expected no recap on a Saturday")
+	}
+
+	task.Run(time.Date(2021, time.June, 27, 9, 5, 0, 0, jerusalem))
+	task.Run(time.Date(2021, time.June, 27, 9, 10, 0, 0, jerusalem))
+	if len(reporter.Messages) != 1 {
+		t.Fatalf("Expected a single recap but got %d", len(reporter.Messages))
+	}
+	expected := "Tel-Aviv's week in UV 📅 The daily peak averaged 6.9, and topped at 9.5 on Thursday.\nLow: 1d. Moderate: 1d. High: 2d. Very High: 3d. Longest High+ streak: 3d."
+	if !strings.HasPrefix(reporter.Messages[0], expected) {
+		t.Errorf("Expected %s to start with %s", reporter.Messages[0], expected)
+	}
+}
+
+func TestRecapTask_Monthly(t *testing.T) {
+	history := openTestHistory(t)
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	recordHistory(t, history, uv.TelAviv, time.Date(2021, time.May, 10, 12, 0, 0, 0, jerusalem), 24*time.Hour, 6, 8)
+	recordHistory(t, history, uv.TelAviv, time.Date(2021, time.June, 10, 12, 0, 0, 0, jerusalem), 24*time.Hour, 9, 10)
+	reporter := &testMessageReporter{}
+	task := &uv.RecapTask{
+		Locations: []*uv.Location{uv.TelAviv},
+		History:   history,
+		Reporters: []uv.MessageReporter{reporter},
+		Period:    uv.RecapMonthly,
+		At:        uv.ClockTime{Hour: 9},
+	}
+
+	task.Run(time.Date(2021, time.June, 30, 9, 5, 0, 0, jerusalem))
+	task.Run(time.Date(2021, time.July, 1, 9, 5, 0, 0, jerusalem))
+	if len(reporter.Messages) != 1 {
+		t.Fatalf("Expected a single recap but got %d", len(reporter.Messages))
+	}
+	expected := "Tel-Aviv's June in UV 📅 The daily peak averaged 9.5, compared to 7.0 in May, and topped at 10.0 on June 11."
+	if !strings.HasPrefix(reporter.Messages[0], expected) {
+		t.Errorf("Expected %s to start with %s", reporter.Messages[0], expected)
+	}
+}
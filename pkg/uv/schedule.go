@@ -0,0 +1,83 @@
+package uv
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// ScheduledTask is run on every tick of RunSchedule and decides by itself
+// whether anything is due.
+type ScheduledTask interface {
+	Run(now time.Time) error
+}
+
+type ScheduleSettings struct {
+	ExitChan     <-chan bool
+	LoopInterval time.Duration
+}
+
+func RunSchedule(tasks []ScheduledTask, scheduleSettings *ScheduleSettings) {
+Loop:
+	for {
+		select {
+		case <-scheduleSettings.ExitChan:
+			log.Println("Received exit signal")
+			break Loop
+		default:
+			now := time.Now()
+			for _, task := range tasks {
+				if taskError := task.Run(now); taskError != nil {
+					log.Println(fmt.Errorf("failed to run scheduled task: %w", taskError))
+				}
+			}
+			time.Sleep(scheduleSettings.LoopInterval)
+		}
+	}
+}
+
+// ClockTime is a time of day in a location's own time zone.
+type ClockTime struct {
+	Hour   int
+	Minute int
+}
+
+func ParseClockTime(value string) (ClockTime, error) {
+	parsed, parseError := time.Parse("15:04", value)
+	if parseError != nil {
+		return ClockTime{}, fmt.Errorf("failed to parse time of day %s: %w", value, parseError)
+	}
+	return ClockTime{Hour: parsed.Hour(), Minute: parsed.Minute()}, nil
+}
+
+func (clockTime ClockTime) String() string {
+	return fmt.Sprintf("%02d:%02d", clockTime.Hour, clockTime.Minute)
+}
+
+// On returns the clock time on the date of day, in the time zone of day.
+func (clockTime ClockTime) On(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), clockTime.Hour, clockTime.Minute, 0, 0, day.Location())
+}
+
+// dailyTrigger fires at most once per local date and location, within a grace
+// period after the configured time so that a bot started late in the day does
+// not post a stale morning briefing.
+type dailyTrigger struct {
+	firedForLocation map[string]string
+}
+
+const dailyTriggerGracePeriod = time.Hour
+
+func (trigger *dailyTrigger) due(location *Location, at time.Time, now time.Time) bool {
+	if now.Before(at) || now.Sub(at) >= dailyTriggerGracePeriod {
+		return false
+	}
+	return trigger.firedForLocation[location.DisplayName] != at.Format("2006-01-02")
+}
+
+func (trigger *dailyTrigger) fired(location *Location, at time.Time) {
+	if trigger.firedForLocation == nil {
+		trigger.firedForLocation = map[string]string{}
+	}
+	trigger.firedForLocation[location.DisplayName] = at.Format("2006-01-02")
+}
@@ -0,0 +1,60 @@
+package uv_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+type testScheduledTask struct {
+	Runs int
+}
+
+func (t *testScheduledTask) Run(now time.Time) error {
+	t.Runs++
+	return nil
+}
+
+func TestRunSchedule(t *testing.T) {
+	task := &testScheduledTask{}
+	exitChan := make(chan bool)
+	scheduleExitChan := make(chan bool)
+	go func() {
+		uv.RunSchedule([]uv.ScheduledTask{task}, &uv.ScheduleSettings{ExitChan: exitChan, LoopInterval: 50 * time.Millisecond})
+		scheduleExitChan <- true
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	close(exitChan)
+
+	select {
+	case <-scheduleExitChan:
+		t.Log("Schedule successfully stopped")
+	case <-time.After(5 * time.Second):
+		t.Error("Never receive a schedule exit message over the channel")
+	}
+	if task.Runs == 0 {
+		t.Error("Scheduled task was never run")
+	}
+}
+
+func TestParseClockTime(t *testing.T) {
+	clockTime, err := uv.ParseClockTime("07:05")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if clockTime.Hour != 7 || clockTime.Minute != 5 {
+		t.Errorf("Expected 07:05 but got %s", clockTime)
+	}
+
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	on := clockTime.On(time.Date(2021, time.June, 21, 23, 0, 0, 0, jerusalem))
+	if !on.Equal(time.Date(2021, time.June, 21, 7, 5, 0, 0, jerusalem)) {
+		t.Errorf("Unexpected time %s", on)
+	}
+
+	if _, err := uv.ParseClockTime("7 in the morning"); err == nil {
+		t.Error("Expected an error")
+	}
+}
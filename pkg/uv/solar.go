@@ -0,0 +1,212 @@
+package uv
+
+import (
+	"math"
+	"time"
+)
+
+// The solar position is computed with the NOAA solar calculator equations,
+// which are based on Jean Meeus' Astronomical Algorithms and are accurate to
+// well under a degree between 1901 and 2099.
+
+type solarCoordinates struct {
+	declination    float64
+	equationOfTime float64
+}
+
+func julianDay(t time.Time) float64 {
+	return float64(t.UTC().UnixNano())/float64(24*time.Hour) + 2440587.5
+}
+
+func computeSolarCoordinates(t time.Time) *solarCoordinates {
+	julianCentury := (julianDay(t) - 2451545) / 36525
+
+	geomMeanLongitude := math.Mod(280.46646+julianCentury*(36000.76983+julianCentury*0.0003032), 360)
+	meanAnomaly := 357.52911 + julianCentury*(35999.05029-0.0001537*julianCentury)
+	eccentricity := 0.016708634 - julianCentury*(0.000042037+0.0000001267*julianCentury)
+
+	meanAnomalyRadians := degreesToRadians(meanAnomaly)
+	equationOfCenter := math.Sin(meanAnomalyRadians)*(1.914602-julianCentury*(0.004817+0.000014*julianCentury)) +
+		math.Sin(2*meanAnomalyRadians)*(0.019993-0.000101*julianCentury) +
+		math.Sin(3*meanAnomalyRadians)*0.000289
+	trueLongitude := geomMeanLongitude + equationOfCenter
+
+	omega := degreesToRadians(125.04 - 1934.136*julianCentury)
+	apparentLongitude := degreesToRadians(trueLongitude - 0.00569 - 0.00478*math.Sin(omega))
+
+	meanObliquity := 23 + (26+(21.448-julianCentury*(46.815+julianCentury*(0.00059-julianCentury*0.001813)))/60)/60
+	obliquity := degreesToRadians(meanObliquity + 0.00256*math.Cos(omega))
+
+	declination := math.Asin(math.Sin(obliquity) * math.Sin(apparentLongitude))
+
+	y := math.Pow(math.Tan(obliquity/2), 2)
+	geomMeanLongitudeRadians := degreesToRadians(geomMeanLongitude)
+	equationOfTime := 4 * radiansToDegrees(y*math.Sin(2*geomMeanLongitudeRadians)-
+		2*eccentricity*math.Sin(meanAnomalyRadians)+
+		4*eccentricity*y*math.Sin(meanAnomalyRadians)*math.Cos(2*geomMeanLongitudeRadians)-
+		0.5*y*y*math.Sin(4*geomMeanLongitudeRadians)-
+		1.25*eccentricity*eccentricity*math.Sin(2*meanAnomalyRadians))
+
+	return &solarCoordinates{declination: declination, equationOfTime: equationOfTime}
+}
+
+// SolarZenithAngle returns the angle in degrees between the sun and the zenith
+// at the given coordinates and time. Values above 90 mean the sun is below the
+// horizon.
+func SolarZenithAngle(latitude float64, longitude float64, t time.Time) float64 {
+	zenith, _ := solarAngles(latitude, longitude, t)
+	return zenith
+}
+
+// SolarElevation returns the angle in degrees of the sun above the horizon,
+// without atmospheric refraction. Negative values mean the sun has set.
+func SolarElevation(latitude float64, longitude float64, t time.Time) float64 {
+	return 90 - SolarZenithAngle(latitude, longitude, t)
+}
+
+// SolarAzimuth returns the direction of the sun in degrees clockwise from
+// north.
+func SolarAzimuth(latitude float64, longitude float64, t time.Time) float64 {
+	_, azimuth := solarAngles(latitude, longitude, t)
+	return azimuth
+}
+
+func solarAngles(latitude float64, longitude float64, t time.Time) (float64, float64) {
+	coordinates := computeSolarCoordinates(t)
+	utc := t.UTC()
+	minutesSinceMidnight := float64(utc.Hour()*60+utc.Minute()) + float64(utc.Second())/60 + float64(utc.Nanosecond())/float64(time.Minute)
+	trueSolarTime := math.Mod(minutesSinceMidnight+coordinates.equationOfTime+4*longitude, 1440)
+	hourAngle := degreesToRadians(trueSolarTime/4 - 180)
+
+	latitudeRadians := degreesToRadians(latitude)
+	cosZenith := math.Sin(latitudeRadians)*math.Sin(coordinates.declination) +
+		math.Cos(latitudeRadians)*math.Cos(coordinates.declination)*math.Cos(hourAngle)
+	zenithRadians := math.Acos(math.Max(-1, math.Min(1, cosZenith)))
+
+	azimuthDenominator := math.Cos(latitudeRadians) * math.Sin(zenithRadians)
+	if azimuthDenominator == 0 {
+		return radiansToDegrees(zenithRadians), 180
+	}
+	cosAzimuth := (math.Sin(latitudeRadians)*math.Cos(zenithRadians) - math.Sin(coordinates.declination)) / azimuthDenominator
+	azimuth := radiansToDegrees(math.Acos(math.Max(-1, math.Min(1, cosAzimuth))))
+	if hourAngle > 0 {
+		azimuth = math.Mod(azimuth+180, 360)
+	} else {
+		azimuth = math.Mod(540-azimuth, 360)
+	}
+	return radiansToDegrees(zenithRadians), azimuth
+}
+
+// sunriseZenith accounts for atmospheric refraction and the radius of the
+// solar disc, so that sunrise is when the upper limb appears.
+const sunriseZenith = 90.833
+
+// SolarDay holds the solar noon, sunrise and sunset of a date. Sunrise and
+// Sunset are zero on days of polar day or night.
+type SolarDay struct {
+	SolarNoon  time.Time
+	Sunrise    time.Time
+	Sunset     time.Time
+	PolarDay   bool
+	PolarNight bool
+}
+
+// DayLength is the time between sunrise and sunset, which is a full day during
+// polar day and zero during polar night.
+func (solarDay *SolarDay) DayLength() time.Duration {
+	if solarDay.PolarDay {
+		return 24 * time.Hour
+	}
+	return solarDay.Sunset.Sub(solarDay.Sunrise)
+}
+
+// ComputeSolarDay returns the solar noon, sunrise and sunset at the given
+// coordinates on the calendar date of day, in the time zone of day.
+func ComputeSolarDay(latitude float64, longitude float64, day time.Time) *SolarDay {
+	utcMidnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	// The equation of time is taken at the approximate noon and refined once
+	approximateNoon := utcMidnight.Add(time.Duration((720 - 4*longitude) * float64(time.Minute)))
+	coordinates := computeSolarCoordinates(approximateNoon)
+	noon := utcMidnight.Add(time.Duration((720 - 4*longitude - coordinates.equationOfTime) * float64(time.Minute)))
+	coordinates = computeSolarCoordinates(noon)
+	noon = utcMidnight.Add(time.Duration((720 - 4*longitude - coordinates.equationOfTime) * float64(time.Minute)))
+
+	solarDay := &SolarDay{SolarNoon: noon.In(day.Location())}
+	latitudeRadians := degreesToRadians(latitude)
+	cosHourAngle := math.Cos(degreesToRadians(sunriseZenith))/(math.Cos(latitudeRadians)*math.Cos(coordinates.declination)) -
+		math.Tan(latitudeRadians)*math.Tan(coordinates.declination)
+	if cosHourAngle < -1 {
+		solarDay.PolarDay = true
+		return solarDay
+	}
+	if cosHourAngle > 1 {
+		solarDay.PolarNight = true
+		return solarDay
+	}
+	halfDay := time.Duration(4 * radiansToDegrees(math.Acos(cosHourAngle)) * float64(time.Minute))
+	solarDay.Sunrise = noon.Add(-halfDay).In(day.Location())
+	solarDay.Sunset = noon.Add(halfDay).In(day.Location())
+	return solarDay
+}
+
+// SolarPosition is where the sun is in the sky of a location.
+type SolarPosition struct {
+	Time time.Time
+	// Elevation is in degrees above the horizon
+	Elevation float64
+	// Azimuth is in degrees clockwise from north
+	Azimuth float64
+}
+
+// ShadowRatio is the length of a shadow relative to the height of whatever
+// casts it, which is infinite when the sun is down.
+func (position *SolarPosition) ShadowRatio() float64 {
+	if position.Elevation <= 0 {
+		return math.Inf(1)
+	}
+	return 1 / math.Tan(degreesToRadians(position.Elevation))
+}
+
+// ShadowShorterThanYou is the WHO shadow rule: when your shadow is shorter
+// than you are, the sun is higher than 45 degrees and the UV is strong.
+func (position *SolarPosition) ShadowShorterThanYou() bool {
+	return position.Elevation > 45
+}
+
+// SolarPosition returns the position of the sun in the sky of the location.
+func (location *Location) SolarPosition(t time.Time) (*SolarPosition, error) {
+	latitude, longitude, coordinatesError := location.Coordinates()
+	if coordinatesError != nil {
+		return nil, coordinatesError
+	}
+	zenith, azimuth := solarAngles(latitude, longitude, t)
+	return &SolarPosition{Time: t, Elevation: 90 - zenith, Azimuth: azimuth}, nil
+}
+
+// SolarDay returns the solar noon, sunrise and sunset of the location on the
+// local date of day, in the location's time zone.
+func (location *Location) SolarDay(day time.Time) (*SolarDay, error) {
+	latitude, longitude, coordinatesError := location.Coordinates()
+	if coordinatesError != nil {
+		return nil, coordinatesError
+	}
+	timeZone, timeZoneError := GetLocation(location.IANA)
+	if timeZoneError != nil {
+		return nil, timeZoneError
+	}
+	return ComputeSolarDay(latitude, longitude, day.In(timeZone)), nil
+}
+
+// EarthSunDistanceFactor is the ratio of the solar irradiance at time t to the
+// irradiance at the mean Earth-Sun distance.
+func EarthSunDistanceFactor(t time.Time) float64 {
+	return 1 + 0.033*math.Cos(2*math.Pi*float64(t.UTC().YearDay())/365)
+}
+
+func degreesToRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func radiansToDegrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
@@ -0,0 +1,125 @@
+package uv_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func TestSolarZenithAngle(t *testing.T) {
+	// Around the June solstice the sun is almost overhead at the Tropic of Cancer at local solar noon
+	zenith := uv.SolarZenithAngle(23.44, 0, time.Date(2021, time.June, 21, 12, 2, 0, 0, time.UTC))
+	if zenith > 0.5 {
+		t.Errorf("Expected the sun to be overhead but got a zenith angle of %.2f", zenith)
+	}
+
+	// Tel-Aviv at noon local time in December
+	zenith = uv.SolarZenithAngle(32.1, 34.85, time.Date(2021, time.December, 21, 9, 40, 0, 0, time.UTC))
+	if math.Abs(zenith-55.5) > 0.5 {
+		t.Errorf("Expected a zenith angle of about %.1f but got %.2f", 55.5, zenith)
+	}
+
+	// Tel-Aviv at midnight
+	zenith = uv.SolarZenithAngle(32.1, 34.85, time.Date(2021, time.December, 21, 22, 0, 0, 0, time.UTC))
+	if zenith < 90 {
+		t.Errorf("Expected the sun to be below the horizon but got a zenith angle of %.2f", zenith)
+	}
+}
+
+func TestEarthSunDistanceFactor(t *testing.T) {
+	perihelion := uv.EarthSunDistanceFactor(time.Date(2021, time.January, 3, 0, 0, 0, 0, time.UTC))
+	aphelion := uv.EarthSunDistanceFactor(time.Date(2021, time.July, 4, 0, 0, 0, 0, time.UTC))
+	if perihelion <= 1 || aphelion >= 1 {
+		t.Errorf("Expected more irradiance at perihelion (%.3f) than at aphelion (%.3f)", perihelion, aphelion)
+	}
+}
+
+func TestSolarAzimuth(t *testing.T) {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	morning := uv.SolarAzimuth(32.1, 34.85, time.Date(2021, time.March, 20, 7, 0, 0, 0, jerusalem))
+	if morning < 90 || morning > 120 {
+		t.Errorf("Expected the sun to be in the east in the morning but got an azimuth of %.2f", morning)
+	}
+	noon := uv.SolarAzimuth(32.1, 34.85, time.Date(2021, time.March, 20, 11, 40, 0, 0, jerusalem))
+	if math.Abs(noon-180) > 5 {
+		t.Errorf("Expected the sun to be in the south at noon but got an azimuth of %.2f", noon)
+	}
+	evening := uv.SolarAzimuth(32.1, 34.85, time.Date(2021, time.March, 20, 17, 0, 0, 0, jerusalem))
+	if evening < 240 || evening > 270 {
+		t.Errorf("Expected the sun to be in the west in the evening but got an azimuth of %.2f", evening)
+	}
+}
+
+func TestComputeSolarDay(t *testing.T) {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	solarDay := uv.ComputeSolarDay(32.109333, 34.855499, time.Date(2021, time.June, 21, 0, 0, 0, 0, jerusalem))
+	expected := map[string]time.Time{
+		"solar noon": time.Date(2021, time.June, 21, 12, 42, 0, 0, jerusalem),
+		"sunrise":    time.Date(2021, time.June, 21, 5, 33, 0, 0, jerusalem),
+		"sunset":     time.Date(2021, time.June, 21, 19, 50, 0, 0, jerusalem),
+	}
+	actual := map[string]time.Time{"solar noon": solarDay.SolarNoon, "sunrise": solarDay.Sunrise, "sunset": solarDay.Sunset}
+	for name, expectedTime := range expected {
+		if difference := actual[name].Sub(expectedTime); difference < -2*time.Minute || difference > 2*time.Minute {
+			t.Errorf("Expected the %s at about %s but got %s", name, expectedTime.Format("15:04"), actual[name].In(jerusalem).Format("15:04"))
+		}
+	}
+	if solarDay.SolarNoon.Location() != jerusalem {
+		t.Errorf("Expected the times in the time zone of the day but got %s", solarDay.SolarNoon.Location())
+	}
+	if solarDay.DayLength() < 14*time.Hour || solarDay.DayLength() > 14*time.Hour+30*time.Minute {
+		t.Errorf("Unexpected day length %s", solarDay.DayLength())
+	}
+
+	polarDay := uv.ComputeSolarDay(80, 15, time.Date(2021, time.June, 21, 0, 0, 0, 0, time.UTC))
+	if !polarDay.PolarDay || !polarDay.Sunrise.IsZero() || polarDay.DayLength() != 24*time.Hour {
+		t.Errorf("Expected a polar day but got %+v", polarDay)
+	}
+	polarNight := uv.ComputeSolarDay(80, 15, time.Date(2021, time.December, 21, 0, 0, 0, 0, time.UTC))
+	if !polarNight.PolarNight || polarNight.DayLength() != 0 {
+		t.Errorf("Expected a polar night but got %+v", polarNight)
+	}
+}
+
+func TestLocation_SolarPosition(t *testing.T) {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	summerNoon, err := uv.TelAviv.SolarPosition(time.Date(2021, time.June, 21, 12, 42, 0, 0, jerusalem))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if math.Abs(summerNoon.Elevation-81.3) > 0.5 || !summerNoon.ShadowShorterThanYou() || summerNoon.ShadowRatio() > 0.2 {
+		t.Errorf("Expected a short shadow under a high sun but got %+v", summerNoon)
+	}
+
+	winterNoon, _ := uv.TelAviv.SolarPosition(time.Date(2021, time.December, 21, 11, 40, 0, 0, jerusalem))
+	if winterNoon.ShadowShorterThanYou() || winterNoon.ShadowRatio() < 1 {
+		t.Errorf("Expected a long shadow under a low sun but got %+v", winterNoon)
+	}
+
+	night, _ := uv.TelAviv.SolarPosition(time.Date(2021, time.December, 21, 23, 0, 0, 0, jerusalem))
+	if night.Elevation > 0 || !math.IsInf(night.ShadowRatio(), 1) {
+		t.Errorf("Expected the sun to be down but got %+v", night)
+	}
+
+	if _, err := (&uv.Location{DisplayName: "test", Latitude: 222.222, Longitude: 34.85}).SolarPosition(time.Now()); err == nil {
+		t.Error("Expected an error for invalid coordinates")
+	}
+}
+
+func TestRenderTemplate_Solar(t *testing.T) {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	data := struct {
+		Location *uv.Location
+		At       time.Time
+	}{Location: uv.TelAviv, At: time.Date(2021, time.June, 21, 12, 0, 0, 0, jerusalem)}
+	text := "{{clock (solarday .Location .At).SolarNoon}}{{if (sun .Location .At).ShadowShorterThanYou}} short shadow{{end}}"
+	rendered, err := uv.RenderTemplate("test", text, data)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if rendered != "12:42 short shadow" {
+		t.Errorf("Expected %s but got %s", "12:42 short shadow", rendered)
+	}
+}
@@ -0,0 +1,172 @@
+package uv
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const dailyLogRetention = 2
+
+// DailyLog is a MeasurementRecorder that keeps every measurement of today and
+// yesterday, grouped by the local date of each location.
+type DailyLog struct {
+	mutex             sync.Mutex
+	measurementsByDay map[string]map[string][]*Measurement
+}
+
+func NewDailyLog() *DailyLog {
+	return &DailyLog{measurementsByDay: map[string]map[string][]*Measurement{}}
+}
+
+func (dailyLog *DailyLog) Record(location *Location, measurement *Measurement) error {
+	timeZone, timeZoneError := GetLocation(location.IANA)
+	if timeZoneError != nil {
+		return timeZoneError
+	}
+	localObservedAt := measurement.ObservedAt.In(timeZone)
+	day := localObservedAt.Format("2006-01-02")
+
+	dailyLog.mutex.Lock()
+	defer dailyLog.mutex.Unlock()
+	days, found := dailyLog.measurementsByDay[location.DisplayName]
+	if !found {
+		days = map[string][]*Measurement{}
+		dailyLog.measurementsByDay[location.DisplayName] = days
+	}
+	days[day] = append(days[day], measurement)
+
+	oldestKept := localObservedAt.AddDate(0, 0, -dailyLogRetention+1).Format("2006-01-02")
+	for recordedDay := range days {
+		if recordedDay < oldestKept {
+			delete(days, recordedDay)
+		}
+	}
+	return nil
+}
+
+// Measurements returns the measurements of the location on the local date of
+// day, sorted by observation time.
+func (dailyLog *DailyLog) Measurements(location *Location, day time.Time) []*Measurement {
+	dailyLog.mutex.Lock()
+	defer dailyLog.mutex.Unlock()
+	recorded := dailyLog.measurementsByDay[location.DisplayName][day.Format("2006-01-02")]
+	measurements := append([]*Measurement{}, recorded...)
+	sort.Slice(measurements, func(i, j int) bool {
+		return measurements[i].ObservedAt.Before(measurements[j].ObservedAt)
+	})
+	return measurements
+}
+
+// DailySummary is the observed UV of a single day. Times are in the
+// location's time zone.
+type DailySummary struct {
+	Location       *Location
+	Date           time.Time
+	PeakUVIndex    float32
+	PeakRawUVIndex *float32
+	PeakTime       time.Time
+	TimeInCategory map[Category]time.Duration
+	DoseSED        float64
+	// Yesterday is nil when there are no measurements of the previous day
+	Yesterday *DailySummary
+}
+
+// PeakChange is the difference between today's peak and yesterday's.
+func (summary *DailySummary) PeakChange() float32 {
+	if summary.Yesterday == nil {
+		return 0
+	}
+	return summary.PeakUVIndex - summary.Yesterday.PeakUVIndex
+}
+
+// NewDailySummary summarizes measurements sorted by observation time. The time
+// between two measurements is attributed to the category of the first one,
+// unless they are more than historyMaxGap apart.
+func NewDailySummary(location *Location, day time.Time, measurements []*Measurement) (*DailySummary, error) {
+	if len(measurements) == 0 {
+		return nil, fmt.Errorf("no measurements of %s were recorded on %s", location.DisplayName, day.Format("2006-01-02"))
+	}
+	summary := &DailySummary{
+		Location:       location,
+		Date:           time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location()),
+		TimeInCategory: map[Category]time.Duration{},
+		DoseSED:        DoseSED(measurements),
+	}
+	for i, measurement := range measurements {
+		if i == 0 || measurement.UVIndex > summary.PeakUVIndex {
+			summary.PeakUVIndex = measurement.UVIndex
+			summary.PeakRawUVIndex = measurement.RawUVIndex
+			summary.PeakTime = measurement.ObservedAt.In(day.Location())
+		}
+		if i > 0 {
+			previous := measurements[i-1]
+			if gap := measurement.ObservedAt.Sub(previous.ObservedAt); gap <= historyMaxGap {
+				summary.TimeInCategory[CategoryOf(previous.UVIndex)] += gap
+			}
+		}
+	}
+	return summary, nil
+}
+
+// EveningSummaryTask posts a summary of the day at sunset. The sunset is taken
+// from the day's measurements, and when none of them carry it, from
+// FallbackTime.
+type EveningSummaryTask struct {
+	Locations    []*Location
+	Log          *DailyLog
+	Reporters    []MessageReporter
+	FallbackTime ClockTime
+
+	trigger dailyTrigger
+}
+
+func (task *EveningSummaryTask) Run(now time.Time) error {
+	for _, location := range task.Locations {
+		if summaryError := task.summarize(location, now); summaryError != nil {
+			log.Println(fmt.Errorf("failed to post the evening summary of %s: %w", location.DisplayName, summaryError))
+		}
+	}
+	return nil
+}
+
+func (task *EveningSummaryTask) summarize(location *Location, now time.Time) error {
+	timeZone, timeZoneError := GetLocation(location.IANA)
+	if timeZoneError != nil {
+		return timeZoneError
+	}
+	localNow := now.In(timeZone)
+	measurements := task.Log.Measurements(location, localNow)
+
+	at := task.FallbackTime.On(localNow)
+	for _, measurement := range measurements {
+		if !measurement.Sunset.IsZero() {
+			at = measurement.Sunset.In(timeZone)
+		}
+	}
+	if !task.trigger.due(location, at, localNow) {
+		return nil
+	}
+	task.trigger.fired(location, at)
+
+	summary, summaryError := NewDailySummary(location, localNow, measurements)
+	if summaryError != nil {
+		return summaryError
+	}
+	yesterday := localNow.AddDate(0, 0, -1)
+	if yesterdaysMeasurements := task.Log.Measurements(location, yesterday); len(yesterdaysMeasurements) > 0 {
+		summary.Yesterday, _ = NewDailySummary(location, yesterday, yesterdaysMeasurements)
+	}
+
+	templates, templatesError := getTemplates(location)
+	if templatesError != nil {
+		return templatesError
+	}
+	message, renderError := RenderTemplate("evening summary", templates.EveningSummary, summary)
+	if renderError != nil {
+		return renderError
+	}
+	return reportMessage(task.Reporters, location, message)
+}
@@ -0,0 +1,129 @@
+package uv_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func recordDay(t *testing.T, dailyLog *uv.DailyLog, start time.Time, sunset time.Time, uvIndices ...float32) {
+	for i, uvIndex := range uvIndices {
+		measurement := &uv.Measurement{ObservedAt: start.Add(time.Duration(i) * 30 * time.Minute), UVIndex: uvIndex, Sunset: sunset}
+		if err := dailyLog.Record(uv.TelAviv, measurement); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDailyLog(t *testing.T) {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	dailyLog := uv.NewDailyLog()
+	firstDay := time.Date(2021, time.June, 19, 10, 0, 0, 0, jerusalem)
+	recordDay(t, dailyLog, firstDay, time.Time{}, 5, 6)
+	recordDay(t, dailyLog, firstDay.AddDate(0, 0, 1), time.Time{}, 7)
+	recordDay(t, dailyLog, firstDay.AddDate(0, 0, 2), time.Time{}, 8, 9)
+
+	if measurements := dailyLog.Measurements(uv.TelAviv, firstDay.AddDate(0, 0, 2)); len(measurements) != 2 {
+		t.Errorf("Expected %d measurements today but got %d", 2, len(measurements))
+	}
+	if measurements := dailyLog.Measurements(uv.TelAviv, firstDay.AddDate(0, 0, 1)); len(measurements) != 1 {
+		t.Errorf("Expected %d measurements yesterday but got %d", 1, len(measurements))
+	}
+	if measurements := dailyLog.Measurements(uv.TelAviv, firstDay); len(measurements) != 0 {
+		t.Errorf("Expected older measurements to be dropped but got %d", len(measurements))
+	}
+}
+
+func TestNewDailySummary(t *testing.T) {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	start := time.Date(2021, time.June, 21, 10, 0, 0, 0, jerusalem)
+	measurements := []*uv.Measurement{
+		{ObservedAt: start, UVIndex: 5},
+		{ObservedAt: start.Add(time.Hour), UVIndex: 9},
+		{ObservedAt: start.Add(90 * time.Minute), UVIndex: 7},
+	}
+	summary, err := uv.NewDailySummary(uv.TelAviv, start, measurements)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if summary.PeakUVIndex != 9 || summary.PeakTime.Format("15:04") != "11:00" {
+		t.Errorf("Expected a peak of 9 at 11:00 but got %.1f at %s", summary.PeakUVIndex, summary.PeakTime.Format("15:04"))
+	}
+	if summary.TimeInCategory[uv.CategoryModerate] != time.Hour {
+		t.Errorf("Expected an hour of Moderate but got %s", summary.TimeInCategory[uv.CategoryModerate])
+	}
+	if summary.TimeInCategory[uv.CategoryVeryHigh] != 30*time.Minute {
+		t.Errorf("Expected 30 minutes of Very High but got %s", summary.TimeInCategory[uv.CategoryVeryHigh])
+	}
+	if math.Abs(summary.DoseSED-uv.DoseSED(measurements)) > 0.0001 {
+		t.Errorf("Unexpected dose %.2f", summary.DoseSED)
+	}
+	if summary.PeakChange() != 0 {
+		t.Errorf("Expected no peak change without yesterday but got %.1f", summary.PeakChange())
+	}
+
+	withOutage := append(measurements, &uv.Measurement{ObservedAt: start.Add(5 * time.Hour), UVIndex: 2})
+	summary, err = uv.NewDailySummary(uv.TelAviv, start, withOutage)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if summary.TimeInCategory[uv.CategoryHigh] != 0 {
+		t.Errorf("Expected an outage not to be attributed to High but got %s", summary.TimeInCategory[uv.CategoryHigh])
+	}
+
+	if _, err := uv.NewDailySummary(uv.TelAviv, start, nil); err == nil {
+		t.Error("Expected an error without measurements")
+	}
+}
+
+func TestEveningSummaryTask(t *testing.T) {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	dailyLog := uv.NewDailyLog()
+	today := time.Date(2021, time.June, 21, 10, 0, 0, 0, jerusalem)
+	sunset := time.Date(2021, time.June, 21, 19, 48, 0, 0, jerusalem)
+	recordDay(t, dailyLog, today.AddDate(0, 0, -1), time.Time{}, 6, 8.5, 7)
+	recordDay(t, dailyLog, today, sunset, 6, 9.2, 7, 4)
+
+	reporter := &testMessageReporter{}
+	task := &uv.EveningSummaryTask{
+		Locations:    []*uv.Location{uv.TelAviv},
+		Log:          dailyLog,
+		Reporters:    []uv.MessageReporter{reporter},
+		FallbackTime: uv.ClockTime{Hour: 19},
+	}
+
+	task.Run(time.Date(2021, time.June, 21, 19, 30, 0, 0, jerusalem))
+	if len(reporter.Messages) != 0 {
+		t.Error("Expected no summary before sunset")
+	}
+	task.Run(time.Date(2021, time.June, 21, 19, 50, 0, 0, jerusalem))
+	task.Run(time.Date(2021, time.June, 21, 19, 51, 0, 0, jerusalem))
+	if len(reporter.Messages) != 1 {
+		t.Fatalf("Expected a single summary but got %d", len(reporter.Messages))
+	}
+	expected := "Good evening Tel-Aviv! 🌇 UV peaked at 9.2 at 10:30, compared to 8.5 yesterday.\nHigh: 1h00m. Very High: 0h30m. Full sun all day would have been 9.5 SED."
+	if !strings.HasPrefix(reporter.Messages[0], expected) {
+		t.Errorf("Expected %s to start with %s", reporter.Messages[0], expected)
+	}
+}
+
+func TestEveningSummaryTask_FallbackTime(t *testing.T) {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	dailyLog := uv.NewDailyLog()
+	recordDay(t, dailyLog, time.Date(2021, time.June, 21, 10, 0, 0, 0, jerusalem), time.Time{}, 6, 7)
+
+	reporter := &testMessageReporter{}
+	task := &uv.EveningSummaryTask{
+		Locations:    []*uv.Location{uv.TelAviv},
+		Log:          dailyLog,
+		Reporters:    []uv.MessageReporter{reporter},
+		FallbackTime: uv.ClockTime{Hour: 19},
+	}
+	task.Run(time.Date(2021, time.June, 21, 19, 0, 0, 0, jerusalem))
+	if len(reporter.Messages) != 1 || strings.Contains(reporter.Messages[0], "yesterday") {
+		t.Errorf("Expected a single summary without yesterday but got %v", reporter.Messages)
+	}
+}
@@ -0,0 +1,146 @@
+package uv
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+)
+
+// Templates are the text/template messages posted for a location, in addition
+// to its Alerts. Every template is rendered with the data type documented next
+// to it.
+type Templates struct {
+	// MorningBriefing is rendered with a *MorningBriefing
+	MorningBriefing string
+	// AdvanceWarning is rendered with an *AdvanceWarning
+	AdvanceWarning string
+	// EveningSummary is rendered with a *DailySummary
+	EveningSummary string
+	// YearlyHighMilestone, EarliestCategoryMilestone and
+	// ClimatologyMilestone are rendered with a *Milestone of their kind
+	YearlyHighMilestone       string
+	EarliestCategoryMilestone string
+	ClimatologyMilestone      string
+	// WeeklyRecap and MonthlyRecap are rendered with a *Recap
+	WeeklyRecap  string
+	MonthlyRecap string
+	// DoseThreshold is rendered with a *DoseAlert
+	DoseThreshold string
+	// VitaminDAlert replaces the Low alert when set and the UV index is above
+	// zero, and is rendered with a *VitaminDGuidance
+	VitaminDAlert string
+	// BurnTimeAlert replaces the High alert when set, and is rendered with a
+	// *BurnTimeGuidance
+	BurnTimeAlert string
+}
+
+var TemplatesByLocation = map[string]*Templates{
+	TelAviv.DisplayName: TelAvivTemplates,
+}
+
+var TelAvivTemplates = &Templates{
+	MorningBriefing: `Good morning Tel-Aviv! ☀️ UV will peak at {{uv .PeakUVIndex}}{{raw .PeakUVIndex .PeakRawUVIndex}} around {{clock .PeakTime}}.
+{{- if .NeedsProtection}} Protect yourself between {{clock .ProtectionStart}} and {{clock .ProtectionEnd}}, {{skin 2}} burns in {{approx (burntime 2 .PeakUVIndex)}} at the peak.{{else}} No sun protection needed today.{{end}} Solar noon is at {{clock (solarday .Location .Date).SolarNoon}}.
+#uvindex #telaviv #uvbot_{{.Date.Unix}}`,
+	AdvanceWarning: `Heads up Tel-Aviv! UV will reach {{.Category}} around {{clock .At}}. Time to find some shade and sun screen 🧴{{if (sun .Location .At).ShadowShorterThanYou}} Your shadow will be shorter than you, a sure sign of strong UV.{{end}}
+#uvindex #telaviv #uvbot_{{.At.Unix}}`,
+	EveningSummary: `Good evening Tel-Aviv! 🌇 UV peaked at {{uv .PeakUVIndex}}{{raw .PeakUVIndex .PeakRawUVIndex}} at {{clock .PeakTime}}{{with .Yesterday}}, compared to {{uv .PeakUVIndex}} yesterday{{end}}.
+{{range $category, $duration := .TimeInCategory}}{{if $duration}}{{$category}}: {{hours $duration}}. {{end}}{{end}}Full sun all day would have been {{printf "%.1f" .DoseSED}} SED.
+#uvindex #telaviv #uvbot_{{.Date.Unix}}`,
+	YearlyHighMilestone: `New record for {{.At.Year}}! 📈 UV in Tel-Aviv reached {{uv .UVIndex}}{{raw .UVIndex .RawUVIndex}} at {{clock .At}}, the highest so far this year. The previous high was {{uv .PreviousHigh}}.
+#uvindex #telaviv #uvbot_{{.At.Unix}}`,
+	EarliestCategoryMilestone: `UV in Tel-Aviv reached {{.Category}} today, the earliest date in our records. Last time it took until {{date .PreviousEarliest}} {{.PreviousEarliest.Year}} 🔥
+#uvindex #telaviv #uvbot_{{.At.Unix}}`,
+	ClimatologyMilestone: `UV in Tel-Aviv is {{uv .UVIndex}}{{raw .UVIndex .RawUVIndex}}, {{uv .Anomaly}} points above the {{.Climatology.Years}}-year {{.At.Month}} average of {{uv .Climatology.AverageDailyPeak}} 🌡️
+#uvindex #telaviv #uvbot_{{.At.Unix}}`,
+	WeeklyRecap: `Tel-Aviv's week in UV 📅 The daily peak averaged {{uv .AverageDailyPeak}}{{with .Previous}}, compared to {{uv .AverageDailyPeak}} the week before{{end}}, and topped at {{uv .MaxUVIndex}} on {{.MaxTime.Weekday}}.
+{{range $category, $days := .DaysInCategory}}{{$category}}: {{$days}}d. {{end}}Longest High+ streak: {{.LongestHighStreak}}d.
+#uvindex #telaviv #uvbot_{{.End.Unix}}`,
+	MonthlyRecap: `Tel-Aviv's {{.Start.Month}} in UV 📅 The daily peak averaged {{uv .AverageDailyPeak}}{{with .Previous}}, compared to {{uv .AverageDailyPeak}} in {{.Start.Month}}{{end}}, and topped at {{uv .MaxUVIndex}} on {{date .MaxTime}}.
+{{range $category, $days := .DaysInCategory}}{{$category}}: {{$days}}d. {{end}}Longest High+ streak: {{.LongestHighStreak}}d.
+#uvindex #telaviv #uvbot_{{.End.Unix}}`,
+	DoseThreshold: `You'd already have received {{printf "%.1f" .Dose.SED}} SED ({{printf "%.0f" .Dose.JoulesPerSquareMeter}} J/m²) of UV in full sun in Tel-Aviv today ☀️ Unprotected {{skin 2}} burns from {{med 2}} SED.
+#uvindex #telaviv #uvbot_{{.Dose.UpdatedAt.Unix}}`,
+}
+
+// TelAvivVitaminDAlert is the VitaminDAlert of Tel-Aviv, which is left out of
+// TelAvivTemplates until the bot opts in.
+var TelAvivVitaminDAlert = `The UV index in Tel-Aviv is {{uv .Measurement.UVIndex}}{{raw .Measurement.UVIndex .Measurement.RawUVIndex}}. It's safe to go outside! 😎 With face and arms bare, {{skin 3}} makes {{printf "%.0f" .TargetIU}} IU of vitamin D in {{approx (.ExposureTime 3)}}.
+#uvindex #telaviv #uvbot_{{.PostedAt.Unix}}`
+
+var templateFunctions = template.FuncMap{
+	"uv": func(uvIndex float32) string {
+		return fmt.Sprintf("%.1f", uvIndex)
+	},
+	// raw takes a corrected UV index and its raw value, which may be nil
+	"raw": rawNote,
+	"clock": func(t time.Time) string {
+		return t.Format("15:04")
+	},
+	"date": func(t time.Time) string {
+		return t.Format("January 2")
+	},
+	// burntime takes a skin type from 1 to 6, a UV index and an optional SPF
+	"burntime": func(skinType int, uvIndex float32, spf ...float32) time.Duration {
+		if len(spf) > 0 {
+			return BurnTime(SkinType(skinType), uvIndex, spf[0])
+		}
+		return BurnTime(SkinType(skinType), uvIndex, 1)
+	},
+	"med": func(skinType int) float64 {
+		return SkinType(skinType).MinimalErythemalDoseSED()
+	},
+	// vitamind takes a skin type from 1 to 6, a UV index and an optional
+	// exposed body fraction
+	"vitamind": func(skinType int, uvIndex float32, bodyFraction ...float64) time.Duration {
+		if len(bodyFraction) > 0 {
+			return VitaminDExposureTime(SkinType(skinType), uvIndex, bodyFraction[0], DefaultVitaminDTargetIU)
+		}
+		return VitaminDExposureTime(SkinType(skinType), uvIndex, BodyFractionFaceAndArms, DefaultVitaminDTargetIU)
+	},
+	"skin": func(skinType int) string {
+		return SkinType(skinType).Description()
+	},
+	"approx": approximateDuration,
+	"sun": func(location *Location, t time.Time) (*SolarPosition, error) {
+		return location.SolarPosition(t)
+	},
+	"solarday": func(location *Location, day time.Time) (*SolarDay, error) {
+		return location.SolarDay(day)
+	},
+	"hours": func(duration time.Duration) string {
+		duration = duration.Round(time.Minute)
+		return fmt.Sprintf("%dh%02dm", int(duration.Hours()), int(duration.Minutes())%60)
+	},
+}
+
+func RenderTemplate(name string, text string, data interface{}) (string, error) {
+	parsedTemplate, parseError := template.New(name).Funcs(templateFunctions).Parse(text)
+	if parseError != nil {
+		return "", fmt.Errorf("failed to parse the %s template: %w", name, parseError)
+	}
+	var rendered bytes.Buffer
+	if executeError := parsedTemplate.Execute(&rendered, data); executeError != nil {
+		return "", fmt.Errorf("failed to render the %s template: %w", name, executeError)
+	}
+	return rendered.String(), nil
+}
+
+func (templates *Templates) milestone(kind MilestoneKind) string {
+	switch kind {
+	case MilestoneYearlyHigh:
+		return templates.YearlyHighMilestone
+	case MilestoneEarliestCategory:
+		return templates.EarliestCategoryMilestone
+	}
+	return templates.ClimatologyMilestone
+}
+
+func getTemplates(location *Location) (*Templates, error) {
+	templates, found := TemplatesByLocation[location.DisplayName]
+	if !found {
+		return nil, fmt.Errorf("no templates were configured for %s", location.DisplayName)
+	}
+	return templates, nil
+}
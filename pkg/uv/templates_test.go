@@ -0,0 +1,38 @@
+package uv_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	data := struct {
+		UVIndex float32
+		Time    time.Time
+	}{UVIndex: 7.25, Time: time.Date(2021, time.June, 21, 9, 40, 0, 0, time.UTC)}
+
+	rendered, err := uv.RenderTemplate("test", "UV {{uv .UVIndex}} at {{clock .Time}}", data)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if rendered != "UV 7.2 at 09:40" {
+		t.Errorf("Expected %s but got %s", "UV 7.2 at 09:40", rendered)
+	}
+
+	if _, err := uv.RenderTemplate("test", "{{.Missing", data); err == nil {
+		t.Error("Expected a parse error")
+	}
+	if _, err := uv.RenderTemplate("test", "{{.Missing}}", data); err == nil {
+		t.Error("Expected a render error")
+	}
+}
+
+func TestTemplatesByLocation(t *testing.T) {
+	for _, location := range uv.Locations {
+		if uv.TemplatesByLocation[location.DisplayName] == nil {
+			t.Errorf("Expected templates for %s", location.DisplayName)
+		}
+	}
+}
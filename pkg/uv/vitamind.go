@@ -0,0 +1,59 @@
+package uv
+
+import "time"
+
+// DefaultVitaminDTargetIU is the amount of vitamin D that the exposure times
+// aim for.
+const DefaultVitaminDTargetIU = 1000
+
+// Fractions of the body surface that are exposed to the sun, following the
+// rule of nines.
+const (
+	BodyFractionFaceAndHands = 0.09
+	BodyFractionFaceAndArms  = 0.25
+	BodyFractionSwimwear     = 0.85
+)
+
+// vitaminDReference is the commonly cited reference exposure: a quarter of the
+// body exposed to a quarter of the minimal erythemal dose makes about 1000 IU
+// of vitamin D.
+const (
+	vitaminDReferenceIU           = 1000
+	vitaminDReferenceBodyFraction = 0.25
+	vitaminDReferenceMEDFraction  = 0.25
+)
+
+// VitaminDExposureTime estimates how long skin of the type needs in a constant
+// UV index to make targetIU of vitamin D with bodyFraction of the body exposed.
+// Synthesis is taken as proportional to the erythemal dose and to the exposed
+// area, which overestimates it at a very low sun. The time is zero when the UV
+// index or the skin type cannot make any vitamin D.
+func VitaminDExposureTime(skinType SkinType, uvIndex float32, bodyFraction float64, targetIU float64) time.Duration {
+	if uvIndex <= 0 || bodyFraction <= 0 || skinType.MinimalErythemalDoseSED() == 0 {
+		return 0
+	}
+	requiredSED := skinType.MinimalErythemalDoseSED() * vitaminDReferenceMEDFraction *
+		(targetIU / vitaminDReferenceIU) * (vitaminDReferenceBodyFraction / bodyFraction)
+	seconds := requiredSED * JoulesPerSED / (float64(uvIndex) * ErythemalIrradiancePerUVIndex)
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// VitaminDGuidance is the data of the low UV alert variant, which tells how
+// long to stay outside rather than that it is safe to.
+type VitaminDGuidance struct {
+	Location     *Location
+	Measurement  *Measurement
+	BodyFraction float64
+	TargetIU     float64
+	PostedAt     time.Time
+}
+
+func NewVitaminDGuidance(location *Location, measurement *Measurement) *VitaminDGuidance {
+	return &VitaminDGuidance{Location: location, Measurement: measurement, BodyFraction: BodyFractionFaceAndArms, TargetIU: DefaultVitaminDTargetIU, PostedAt: time.Now()}
+}
+
+// ExposureTime is the time that skin of the type, given as 1 to 6, needs in
+// the measured UV index.
+func (guidance *VitaminDGuidance) ExposureTime(skinType int) time.Duration {
+	return VitaminDExposureTime(SkinType(skinType), guidance.Measurement.UVIndex, guidance.BodyFraction, guidance.TargetIU)
+}
@@ -0,0 +1,77 @@
+package uv_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func TestVitaminDExposureTime(t *testing.T) {
+	tests := []struct {
+		skinType     uv.SkinType
+		uvIndex      float32
+		bodyFraction float64
+		targetIU     float64
+		expected     time.Duration
+	}{
+		{uv.SkinTypeII, 2, 0.25, 1000, 1250 * time.Second},
+		{uv.SkinTypeII, 2, 0.5, 1000, 625 * time.Second},
+		{uv.SkinTypeII, 2, 0.25, 2000, 2500 * time.Second},
+		{uv.SkinTypeVI, 1, 0.25, 1000, 10000 * time.Second},
+		{uv.SkinTypeII, 0, 0.25, 1000, 0},
+		{uv.SkinTypeII, 2, 0, 1000, 0},
+	}
+	for _, test := range tests {
+		exposureTime := uv.VitaminDExposureTime(test.skinType, test.uvIndex, test.bodyFraction, test.targetIU)
+		if exposureTime.Round(time.Second) != test.expected {
+			t.Errorf("Expected skin type %s with %.2f of the body exposed to UV %.1f to make %.0f IU after %s but got %s", test.skinType, test.bodyFraction, test.uvIndex, test.targetIU, test.expected, exposureTime)
+		}
+	}
+}
+
+func TestVitaminDGuidance(t *testing.T) {
+	guidance := uv.NewVitaminDGuidance(uv.TelAviv, &uv.Measurement{UVIndex: 2})
+	if guidance.ExposureTime(2) != uv.VitaminDExposureTime(uv.SkinTypeII, 2, uv.BodyFractionFaceAndArms, uv.DefaultVitaminDTargetIU) {
+		t.Errorf("Unexpected exposure time %s", guidance.ExposureTime(2))
+	}
+
+	rendered, err := uv.RenderTemplate("test", "{{approx (vitamind 2 2)}} {{approx (vitamind 2 2 0.09)}}", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if rendered != "~20 min ~1h00m" {
+		t.Errorf("Expected %s but got %s", "~20 min ~1h00m", rendered)
+	}
+}
+
+func TestVitaminDAlert(t *testing.T) {
+	location := &uv.Location{DisplayName: "vitamin-d-test", IANA: "Asia/Jerusalem", Latitude: 32.1, Longitude: 34.85}
+	uv.AltertsByLocation[location.DisplayName] = uv.TelAvivAlerts{}
+	defer delete(uv.AltertsByLocation, location.DisplayName)
+	uv.TemplatesByLocation[location.DisplayName] = &uv.Templates{VitaminDAlert: uv.TelAvivVitaminDAlert}
+	defer delete(uv.TemplatesByLocation, location.DisplayName)
+
+	reporter := &testMediaReporter{}
+	cardReporter := &uv.ChartCardReporter{Reporters: []uv.MediaReporter{reporter}}
+	cardReporter.Report(location, &uv.Measurement{ObservedAt: time.Now(), UVIndex: 0.5})
+	cardReporter.Report(location, &uv.Measurement{ObservedAt: time.Now(), UVIndex: 0})
+	cardReporter.Report(uv.TelAviv, &uv.Measurement{ObservedAt: time.Now(), UVIndex: 0.5})
+	if len(reporter.Messages) != 3 {
+		t.Fatalf("Expected %d alerts but got %d", 3, len(reporter.Messages))
+	}
+
+	expected := "The UV index in Tel-Aviv is 0.5. It's safe to go outside! 😎 With face and arms bare, medium skin makes 1000 IU of vitamin D in ~1h55m.\n#uvindex #telaviv #uvbot_"
+	if !strings.HasPrefix(reporter.Messages[0], expected) {
+		t.Errorf("Expected %s to start with %s", reporter.Messages[0], expected)
+	}
+	expected = "The UV index in Tel-Aviv is 0.0. It's safe to go outside! 😎\n#uvindex #telaviv #uvbot_"
+	if !strings.HasPrefix(reporter.Messages[1], expected) {
+		t.Errorf("Expected the regular Low alert at night but got %s", reporter.Messages[1])
+	}
+	expected = "The UV index in Tel-Aviv is 0.5. It's safe to go outside! 😎\n#uvindex #telaviv #uvbot_"
+	if !strings.HasPrefix(reporter.Messages[2], expected) {
+		t.Errorf("Expected the regular Low alert without opting in but got %s", reporter.Messages[2])
+	}
+}
@@ -0,0 +1,111 @@
+package uv
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// AdvanceWarning is a forecast-based heads-up that the UV index is about to
+// cross into a higher category. At is in the location's time zone.
+type AdvanceWarning struct {
+	Location *Location
+	Category Category
+	At       time.Time
+}
+
+var DefaultWarningCategories = []Category{CategoryHigh, CategoryVeryHigh}
+
+// AdvanceWarningTask posts a warning LeadTime before the hourly forecast
+// crosses into one of the categories. Every location is warned at most once a
+// day, and never about a category that the latest measurement already reached.
+type AdvanceWarningTask struct {
+	Locations        []*Location
+	ForecastProvider ForecastProvider
+	Reporters        []MessageReporter
+	Categories       []Category
+	LeadTime         time.Duration
+
+	warnedForLocation map[string]string
+}
+
+func (task *AdvanceWarningTask) Run(now time.Time) error {
+	for _, location := range task.Locations {
+		if warningError := task.warn(location, now); warningError != nil {
+			log.Println(fmt.Errorf("failed to post the advance warning of %s: %w", location.DisplayName, warningError))
+		}
+	}
+	return nil
+}
+
+func (task *AdvanceWarningTask) warn(location *Location, now time.Time) error {
+	timeZone, timeZoneError := GetLocation(location.IANA)
+	if timeZoneError != nil {
+		return timeZoneError
+	}
+	localNow := now.In(timeZone)
+	today := localNow.Format("2006-01-02")
+	if task.warnedForLocation[location.DisplayName] == today {
+		return nil
+	}
+
+	forecast, forecastError := task.ForecastProvider.Forecast(location)
+	if forecastError != nil {
+		return fmt.Errorf("failed to get the forecast: %w", forecastError)
+	}
+	warning := task.upcomingWarning(location, forecast, localNow)
+	if warning == nil {
+		return nil
+	}
+
+	templates, templatesError := getTemplates(location)
+	if templatesError != nil {
+		return templatesError
+	}
+	message, renderError := RenderTemplate("advance warning", templates.AdvanceWarning, warning)
+	if renderError != nil {
+		return renderError
+	}
+
+	if task.warnedForLocation == nil {
+		task.warnedForLocation = map[string]string{}
+	}
+	task.warnedForLocation[location.DisplayName] = today
+	return reportMessage(task.Reporters, location, message)
+}
+
+// upcomingWarning returns a warning for the highest category that the
+// forecast crosses into within the lead time, if any.
+func (task *AdvanceWarningTask) upcomingWarning(location *Location, forecast *Forecast, localNow time.Time) *AdvanceWarning {
+	categories := task.Categories
+	if len(categories) == 0 {
+		categories = DefaultWarningCategories
+	}
+	latestMeasurement := LatestMeasurement(location)
+
+	midnight := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, localNow.Location())
+	hourly := forecast.HourlyBetween(midnight, midnight.AddDate(0, 0, 1))
+
+	var warning *AdvanceWarning
+	for _, category := range categories {
+		if latestMeasurement != nil && latestMeasurement.UVIndex >= category.Threshold() {
+			continue
+		}
+		for i := 1; i < len(hourly); i++ {
+			if hourly[i-1].UVIndex >= category.Threshold() || hourly[i].UVIndex < category.Threshold() {
+				continue
+			}
+			crossing := crossingTime(hourly[i-1], hourly[i], category.Threshold())
+			if !crossing.After(localNow) {
+				continue
+			}
+			if !crossing.After(localNow.Add(task.LeadTime)) {
+				if warning == nil || category > warning.Category {
+					warning = &AdvanceWarning{Location: location, Category: category, At: crossing.In(localNow.Location())}
+				}
+			}
+			break
+		}
+	}
+	return warning
+}
@@ -0,0 +1,109 @@
+package uv_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func TestAdvanceWarningTask(t *testing.T) {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	forecast := hourlyForecast(time.Date(2021, time.June, 21, 6, 0, 0, 0, jerusalem), 0.5, 1.5, 4.5, 7, 9.8, 10.1, 9, 7, 5, 2.6, 1, 0)
+	forecastProvider := &testForecastProvider{ForecastForLocation: map[string]*uv.Forecast{uv.TelAviv.DisplayName: forecast}}
+	reporter := &testMessageReporter{}
+	task := &uv.AdvanceWarningTask{
+		Locations:        []*uv.Location{uv.TelAviv},
+		ForecastProvider: forecastProvider,
+		Reporters:        []uv.MessageReporter{reporter},
+		LeadTime:         30 * time.Minute,
+	}
+
+	// High is reached at 08:36 and Very High at 09:21
+	task.Run(time.Date(2021, time.June, 21, 8, 0, 0, 0, jerusalem))
+	if len(reporter.Messages) != 0 {
+		t.Error("Expected no warning before the lead time")
+	}
+
+	task.Run(time.Date(2021, time.June, 21, 8, 15, 0, 0, jerusalem))
+	if len(reporter.Messages) != 1 {
+		t.Fatalf("Expected a warning but got %d", len(reporter.Messages))
+	}
+	expected := "Heads up Tel-Aviv! UV will reach High around 08:36."
+	if !strings.HasPrefix(reporter.Messages[0], expected) {
+		t.Errorf("Expected %s to start with %s", reporter.Messages[0], expected)
+	}
+
+	task.Run(time.Date(2021, time.June, 21, 9, 10, 0, 0, jerusalem))
+	if len(reporter.Messages) != 1 {
+		t.Error("Expected at most one warning per day")
+	}
+}
+
+func TestAdvanceWarningTask_HighestCategory(t *testing.T) {
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	forecast := hourlyForecast(time.Date(2021, time.June, 21, 8, 0, 0, 0, jerusalem), 5, 9)
+	forecastProvider := &testForecastProvider{ForecastForLocation: map[string]*uv.Forecast{uv.TelAviv.DisplayName: forecast}}
+	reporter := &testMessageReporter{}
+	task := &uv.AdvanceWarningTask{
+		Locations:        []*uv.Location{uv.TelAviv},
+		ForecastProvider: forecastProvider,
+		Reporters:        []uv.MessageReporter{reporter},
+		LeadTime:         time.Hour,
+	}
+
+	task.Run(time.Date(2021, time.June, 21, 8, 5, 0, 0, jerusalem))
+	if len(reporter.Messages) != 1 || !strings.Contains(reporter.Messages[0], "Very High around 08:45") {
+		t.Errorf("Expected a single warning about Very High but got %v", reporter.Messages)
+	}
+}
+
+func TestAdvanceWarningTask_AlreadyMeasured(t *testing.T) {
+	location := &uv.Location{DisplayName: "warning-test", IANA: "Asia/Jerusalem", Latitude: 32.1, Longitude: 34.85}
+	uv.TemplatesByLocation[location.DisplayName] = uv.TelAvivTemplates
+	defer delete(uv.TemplatesByLocation, location.DisplayName)
+
+	provider := &testMeasurementProvider{MeasurementForLocation: map[string]float32{location.DisplayName: 6.5}}
+	reporter := &testMeasurementReporter{ReportedLocations: map[string]float32{}}
+	uv.GetMeasureAndReportFunction(provider, reporter)(location)
+
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	forecast := hourlyForecast(time.Date(2021, time.June, 21, 8, 0, 0, 0, jerusalem), 5, 7)
+	forecastProvider := &testForecastProvider{ForecastForLocation: map[string]*uv.Forecast{location.DisplayName: forecast}}
+	messageReporter := &testMessageReporter{}
+	task := &uv.AdvanceWarningTask{
+		Locations:        []*uv.Location{location},
+		ForecastProvider: forecastProvider,
+		Reporters:        []uv.MessageReporter{messageReporter},
+		LeadTime:         time.Hour,
+	}
+
+	task.Run(time.Date(2021, time.June, 21, 8, 5, 0, 0, jerusalem))
+	if len(messageReporter.Messages) != 0 {
+		t.Errorf("Expected no warning about a category that was already measured but got %v", messageReporter.Messages)
+	}
+}
+
+func TestAdvanceWarningTask_AfterPastCrossing(t *testing.T) {
+	location := &uv.Location{DisplayName: "warning-past-test", IANA: "Asia/Jerusalem", Latitude: 32.1, Longitude: 34.85}
+	uv.TemplatesByLocation[location.DisplayName] = uv.TelAvivTemplates
+	defer delete(uv.TemplatesByLocation, location.DisplayName)
+
+	jerusalem, _ := uv.GetLocation("Asia/Jerusalem")
+	// High is crossed at 08:30, left at 09:30 and crossed again at 10:30
+	forecast := hourlyForecast(time.Date(2021, time.June, 21, 8, 0, 0, 0, jerusalem), 5, 7, 5, 7)
+	forecastProvider := &testForecastProvider{ForecastForLocation: map[string]*uv.Forecast{location.DisplayName: forecast}}
+	reporter := &testMessageReporter{}
+	task := &uv.AdvanceWarningTask{
+		Locations:        []*uv.Location{location},
+		ForecastProvider: forecastProvider,
+		Reporters:        []uv.MessageReporter{reporter},
+		LeadTime:         30 * time.Minute,
+	}
+
+	task.Run(time.Date(2021, time.June, 21, 10, 5, 0, 0, jerusalem))
+	if len(reporter.Messages) != 1 || !strings.Contains(reporter.Messages[0], "High around 10:30") {
+		t.Errorf("Expected a warning about the upcoming crossing but got %v", reporter.Messages)
+	}
+}
@@ -0,0 +1,101 @@
+package uv
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const WundergroundUploadPath = "/weatherstation/updateweatherstation.php"
+
+const wundergroundDateLayout = "2006-01-02 15:04:05"
+
+type WundergroundStation struct {
+	Password string
+	Location *Location
+}
+
+// WundergroundReceiver accepts uploads made by personal weather stations using
+// the Weather Underground PWS protocol, and exposes the latest UV reading of
+// each station as a MeasurementProvider. Stations are identified by their ID
+// and must authenticate with their password.
+type WundergroundReceiver struct {
+	Stations map[string]*WundergroundStation
+	MaxAge   time.Duration
+	Now      func() time.Time
+
+	readings latestReadings
+}
+
+func NewWundergroundReceiver(stations map[string]*WundergroundStation, maxAge time.Duration) *WundergroundReceiver {
+	return &WundergroundReceiver{Stations: stations, MaxAge: maxAge}
+}
+
+func (receiver *WundergroundReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+
+	stationID := query.Get("ID")
+	station, found := receiver.Stations[stationID]
+	if !found || subtle.ConstantTimeCompare([]byte(station.Password), []byte(query.Get("PASSWORD"))) != 1 {
+		log.Printf("Rejected Weather Underground upload from station %s\n", stationID)
+		http.Error(w, "INVALIDPASSWORDID|Password or key and/or id are incorrect", http.StatusUnauthorized)
+		return
+	}
+
+	uvParam := query.Get("UV")
+	if uvParam == "" {
+		// Stations without a UV sensor still upload, there is just nothing to record
+		fmt.Fprintln(w, "success")
+		return
+	}
+	uvIndex, parseError := strconv.ParseFloat(uvParam, 32)
+	if parseError != nil {
+		log.Println(fmt.Errorf("failed to parse UV index of Weather Underground upload for %s: %w", station.Location.DisplayName, parseError))
+		http.Error(w, "invalid UV", http.StatusBadRequest)
+		return
+	}
+	if uvIndex < 0 {
+		log.Printf("Rejected negative UV index %.1f of Weather Underground upload for %s\n", uvIndex, station.Location.DisplayName)
+		http.Error(w, "invalid UV", http.StatusBadRequest)
+		return
+	}
+
+	receivedAt := receiver.now()
+	observedAt, dateError := time.Parse(wundergroundDateLayout, query.Get("dateutc"))
+	if dateError != nil {
+		observedAt = receivedAt
+	}
+	receiver.readings.update(station.Location.DisplayName, &StationReading{
+		Station:    stationID,
+		UVIndex:    float32(uvIndex),
+		ObservedAt: observedAt,
+		ReceivedAt: receivedAt,
+	})
+	fmt.Fprintln(w, "success")
+}
+
+func (receiver *WundergroundReceiver) Measure(locationToMeasure *Location) (*Measurement, error) {
+	reading, readingError := receiver.LatestReading(locationToMeasure)
+	if readingError != nil {
+		return nil, readingError
+	}
+	return reading.measurement("wunderground:" + reading.Station), nil
+}
+
+func (receiver *WundergroundReceiver) LatestReading(location *Location) (*StationReading, error) {
+	return receiver.readings.get(location, receiver.MaxAge, receiver.now())
+}
+
+func (receiver *WundergroundReceiver) now() time.Time {
+	if receiver.Now != nil {
+		return receiver.Now()
+	}
+	return time.Now()
+}
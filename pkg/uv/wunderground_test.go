@@ -0,0 +1,96 @@
+package uv_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noamt/uv-bot/pkg/uv"
+)
+
+func uploadToWunderground(receiver *uv.WundergroundReceiver, query url.Values) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, uv.WundergroundUploadPath+"?"+query.Encode(), nil)
+	recorder := httptest.NewRecorder()
+	receiver.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestWundergroundReceiver(t *testing.T) {
+	now := time.Date(2021, time.June, 21, 9, 0, 0, 0, time.UTC)
+	stations := map[string]*uv.WundergroundStation{"ITELAV12": {Password: "secret", Location: uv.TelAviv}}
+	receiver := uv.NewWundergroundReceiver(stations, 10*time.Minute)
+	receiver.Now = func() time.Time { return now }
+
+	query := url.Values{"ID": {"ITELAV12"}, "PASSWORD": {"secret"}, "dateutc": {"now"}, "UV": {"6.4"}, "action": {"updateraw"}}
+	response := uploadToWunderground(receiver, query)
+	if response.Code != http.StatusOK {
+		t.Errorf("Expected status %d but got %d", http.StatusOK, response.Code)
+	}
+	if strings.TrimSpace(response.Body.String()) != "success" {
+		t.Errorf("Expected body %s but got %s", "success", response.Body.String())
+	}
+
+	measurement, err := receiver.Measure(uv.TelAviv)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if measurement.UVIndex != 6.4 {
+		t.Errorf("Expected UV index %.1f but got %.1f", 6.4, measurement.UVIndex)
+	}
+	reading, _ := receiver.LatestReading(uv.TelAviv)
+	if !reading.ObservedAt.Equal(now) {
+		t.Errorf("Expected observation time %s but got %s", now, reading.ObservedAt)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := receiver.Measure(uv.TelAviv); !errors.Is(err, uv.ErrStaleReading) {
+		t.Errorf("Expected %v but got %v", uv.ErrStaleReading, err)
+	}
+}
+
+func TestWundergroundReceiver_WrongPassword(t *testing.T) {
+	stations := map[string]*uv.WundergroundStation{"ITELAV12": {Password: "secret", Location: uv.TelAviv}}
+	receiver := uv.NewWundergroundReceiver(stations, 10*time.Minute)
+
+	response := uploadToWunderground(receiver, url.Values{"ID": {"ITELAV12"}, "PASSWORD": {"guess"}, "UV": {"6"}})
+	if response.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d but got %d", http.StatusUnauthorized, response.Code)
+	}
+	response = uploadToWunderground(receiver, url.Values{"ID": {"IUNKNOWN"}, "PASSWORD": {"secret"}, "UV": {"6"}})
+	if response.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d but got %d", http.StatusUnauthorized, response.Code)
+	}
+	if _, err := receiver.Measure(uv.TelAviv); !errors.Is(err, uv.ErrNoReading) {
+		t.Errorf("Expected %v but got %v", uv.ErrNoReading, err)
+	}
+}
+
+func TestWundergroundReceiver_NoUVSensor(t *testing.T) {
+	stations := map[string]*uv.WundergroundStation{"ITELAV12": {Password: "secret", Location: uv.TelAviv}}
+	receiver := uv.NewWundergroundReceiver(stations, 10*time.Minute)
+
+	response := uploadToWunderground(receiver, url.Values{"ID": {"ITELAV12"}, "PASSWORD": {"secret"}, "tempf": {"80"}})
+	if response.Code != http.StatusOK {
+		t.Errorf("Expected status %d but got %d", http.StatusOK, response.Code)
+	}
+	if _, err := receiver.Measure(uv.TelAviv); !errors.Is(err, uv.ErrNoReading) {
+		t.Errorf("Expected %v but got %v", uv.ErrNoReading, err)
+	}
+
+	response = uploadToWunderground(receiver, url.Values{"ID": {"ITELAV12"}, "PASSWORD": {"secret"}, "UV": {"high"}})
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d but got %d", http.StatusBadRequest, response.Code)
+	}
+
+	response = uploadToWunderground(receiver, url.Values{"ID": {"ITELAV12"}, "PASSWORD": {"secret"}, "UV": {"-9999"}})
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d but got %d", http.StatusBadRequest, response.Code)
+	}
+	if _, err := receiver.Measure(uv.TelAviv); !errors.Is(err, uv.ErrNoReading) {
+		t.Errorf("Expected %v but got %v", uv.ErrNoReading, err)
+	}
+}